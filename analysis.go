@@ -0,0 +1,603 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Interval is the range of values [Lo, Hi] an integer may take. The ends are
+// -unbounded and unbounded when the value is not bounded that way.
+type Interval struct {
+	Lo, Hi int
+}
+
+var topInterval = Interval{Lo: -unbounded, Hi: unbounded}
+
+func singleton(n int) Interval {
+	n = saturate(n)
+	return Interval{Lo: n, Hi: n}
+}
+
+func (i Interval) isSingleton() bool {
+	return i.Lo == i.Hi && abs(i.Lo) < unbounded
+}
+
+func (i Interval) contains(n int) bool {
+	return i.Lo <= n && n <= i.Hi
+}
+
+func (i Interval) overlaps(other Interval) bool {
+	return i.Lo <= other.Hi && other.Lo <= i.Hi
+}
+
+func (i Interval) join(other Interval) Interval {
+	if other.Lo < i.Lo {
+		i.Lo = other.Lo
+	}
+	if other.Hi > i.Hi {
+		i.Hi = other.Hi
+	}
+	return i
+}
+
+// widen moves the ends of i that grew past previous to unbounded.
+func (i Interval) widen(previous Interval) Interval {
+	if i.Lo < previous.Lo {
+		i.Lo = -unbounded
+	}
+	if i.Hi > previous.Hi {
+		i.Hi = unbounded
+	}
+	return i
+}
+
+func (i Interval) String() string {
+	format := func(n int) string {
+		switch {
+		case n >= unbounded:
+			return "+inf"
+		case n <= -unbounded:
+			return "-inf"
+		default:
+			return fmt.Sprint(n)
+		}
+	}
+	if i.Lo == i.Hi {
+		return format(i.Lo)
+	}
+	return "[" + format(i.Lo) + ", " + format(i.Hi) + "]"
+}
+
+// addBound adds two interval ends, keeping an end that is unbounded
+// unbounded.
+func addBound(a, b int) int {
+	if abs(a) >= unbounded {
+		return saturate(a)
+	}
+	if abs(b) >= unbounded {
+		return saturate(b)
+	}
+	return saturate(a + b)
+}
+
+// corners returns the hull of f applied to every pair of ends.
+func corners(lhs, rhs []int, f func(a, b int) int) Interval {
+	result := Interval{Lo: unbounded, Hi: -unbounded}
+	for _, a := range lhs {
+		for _, b := range rhs {
+			result = result.join(singleton(f(a, b)))
+		}
+	}
+	return result
+}
+
+// arithmeticInterval follows the operand order of the instructions: lhs is
+// the top of the stack and rhs the value below it.
+func arithmeticInterval(instruction Instruction, lhs, rhs Interval) Interval {
+	switch instruction.(type) {
+	case Addition:
+		return Interval{Lo: addBound(lhs.Lo, rhs.Lo), Hi: addBound(lhs.Hi, rhs.Hi)}
+	case Subtraction:
+		return Interval{Lo: addBound(lhs.Lo, -rhs.Hi), Hi: addBound(lhs.Hi, -rhs.Lo)}
+	case Multiplication:
+		return corners([]int{lhs.Lo, lhs.Hi}, []int{rhs.Lo, rhs.Hi}, multiply)
+	case Division:
+		// The quotient is largest for the divisors closest to zero.
+		var divisors []int
+		for _, n := range []int{rhs.Lo, rhs.Hi, -1, 1} {
+			if n != 0 && rhs.contains(n) {
+				divisors = append(divisors, n)
+			}
+		}
+		if len(divisors) == 0 {
+			return topInterval
+		}
+		return corners([]int{lhs.Lo, lhs.Hi}, divisors, func(a, b int) int { return a / b })
+	case Modulo:
+		// The remainder has the sign of the dividend and is smaller than
+		// the divisor.
+		bound := abs(rhs.Lo)
+		if abs(rhs.Hi) > bound {
+			bound = abs(rhs.Hi)
+		}
+		bound = addBound(bound, -1)
+		result := Interval{Lo: -bound, Hi: bound}
+		if lhs.Lo >= 0 {
+			result.Lo = 0
+			if lhs.Hi < result.Hi {
+				result.Hi = lhs.Hi
+			}
+		}
+		if lhs.Hi <= 0 {
+			result.Hi = 0
+			if lhs.Lo > result.Lo {
+				result.Lo = lhs.Lo
+			}
+		}
+		return result
+	}
+	return topInterval
+}
+
+// maxWrittenRanges bounds the number of ranges kept for the addresses that
+// may have been written.
+const maxWrittenRanges = 16
+
+// abstractState describes every concrete state possible before an
+// instruction. The stack holds the top values, top last; when deep is set
+// the stack may hold more values below them, which are unknown. cells holds
+// the values of addresses written one at a time and ranged the value of
+// writes to addresses that were not known exactly. initialized is the set
+// of addresses written on every path, written the ranges of addresses
+// written on some path.
+type abstractState struct {
+	stack       []Interval
+	deep        bool
+	cells       map[int]Interval
+	ranged      *Interval
+	initialized map[int]bool
+	written     []Interval
+}
+
+func (state *abstractState) copy() *abstractState {
+	result := &abstractState{
+		stack:       append([]Interval{}, state.stack...),
+		deep:        state.deep,
+		cells:       make(map[int]Interval, len(state.cells)),
+		initialized: make(map[int]bool, len(state.initialized)),
+		written:     append([]Interval{}, state.written...),
+	}
+	if state.ranged != nil {
+		ranged := *state.ranged
+		result.ranged = &ranged
+	}
+	for address, value := range state.cells {
+		result.cells[address] = value
+	}
+	for address := range state.initialized {
+		result.initialized[address] = true
+	}
+	return result
+}
+
+func (state *abstractState) push(value Interval) {
+	state.stack = append(state.stack, value)
+}
+
+func (state *abstractState) pop() Interval {
+	if len(state.stack) == 0 {
+		return topInterval
+	}
+	value := state.stack[len(state.stack)-1]
+	state.stack = state.stack[:len(state.stack)-1]
+	return value
+}
+
+func (state *abstractState) peek(n int) Interval {
+	if n >= len(state.stack) {
+		return topInterval
+	}
+	return state.stack[len(state.stack)-1-n]
+}
+
+// addWritten adds a range to written, merging ranges that overlap or touch
+// and the closest ones once there are more than maxWrittenRanges.
+func addWritten(written []Interval, addresses Interval) []Interval {
+	written = append(append([]Interval{}, written...), addresses)
+	sort.Slice(written, func(i, j int) bool { return written[i].Lo < written[j].Lo })
+
+	merged := written[:1]
+	for _, next := range written[1:] {
+		last := &merged[len(merged)-1]
+		if next.Lo <= saturate(last.Hi+1) {
+			*last = last.join(next)
+		} else {
+			merged = append(merged, next)
+		}
+	}
+
+	for len(merged) > maxWrittenRanges {
+		closest := 0
+		for i := 1; i+1 < len(merged); i++ {
+			if merged[i+1].Lo-merged[i].Hi < merged[closest+1].Lo-merged[closest].Hi {
+				closest = i
+			}
+		}
+		merged[closest] = merged[closest].join(merged[closest+1])
+		merged = append(merged[:closest+1], merged[closest+2:]...)
+	}
+	return merged
+}
+
+func (state *abstractState) write(address, value Interval) {
+	state.written = addWritten(state.written, address)
+	if address.isSingleton() {
+		state.cells[address.Lo] = value
+		state.initialized[address.Lo] = true
+		return
+	}
+
+	if state.ranged == nil {
+		state.ranged = &value
+	} else {
+		joined := state.ranged.join(value)
+		state.ranged = &joined
+	}
+}
+
+// read returns the values a successful read from address may return, or
+// false when no address it may be was written.
+func (state *abstractState) read(address Interval) (Interval, bool) {
+	var result *Interval
+	add := func(value Interval) {
+		if result == nil {
+			result = &value
+		} else {
+			joined := result.join(value)
+			result = &joined
+		}
+	}
+
+	if state.ranged != nil {
+		add(*state.ranged)
+	}
+	if !address.isSingleton() {
+		for cell, value := range state.cells {
+			if address.contains(cell) {
+				add(value)
+			}
+		}
+	} else if value, ok := state.cells[address.Lo]; ok {
+		add(value)
+	}
+
+	if result == nil {
+		return Interval{}, false
+	}
+	return *result, true
+}
+
+func (state *abstractState) join(other *abstractState) *abstractState {
+	result := state.copy()
+
+	depth := len(state.stack)
+	if len(other.stack) != depth {
+		result.deep = true
+		if len(other.stack) < depth {
+			depth = len(other.stack)
+		}
+	}
+	result.deep = result.deep || other.deep
+	result.stack = make([]Interval, depth)
+	for n := 1; n <= depth; n++ {
+		result.stack[depth-n] = state.stack[len(state.stack)-n].join(other.stack[len(other.stack)-n])
+	}
+
+	for address, value := range other.cells {
+		if previous, ok := result.cells[address]; ok {
+			result.cells[address] = previous.join(value)
+		} else {
+			result.cells[address] = value
+		}
+	}
+	if other.ranged != nil {
+		if result.ranged == nil {
+			ranged := *other.ranged
+			result.ranged = &ranged
+		} else {
+			joined := result.ranged.join(*other.ranged)
+			result.ranged = &joined
+		}
+	}
+	for address := range result.initialized {
+		if !other.initialized[address] {
+			delete(result.initialized, address)
+		}
+	}
+	for _, addresses := range other.written {
+		result.written = addWritten(result.written, addresses)
+	}
+
+	return result
+}
+
+// widen moves every value of state that grew since previous to unbounded.
+func (state *abstractState) widen(previous *abstractState) {
+	if len(state.stack) == len(previous.stack) {
+		for n := range state.stack {
+			state.stack[n] = state.stack[n].widen(previous.stack[n])
+		}
+	}
+	for address, value := range state.cells {
+		if old, ok := previous.cells[address]; ok {
+			state.cells[address] = value.widen(old)
+		}
+	}
+	if state.ranged != nil && previous.ranged != nil {
+		widened := state.ranged.widen(*previous.ranged)
+		state.ranged = &widened
+	}
+	if !reflect.DeepEqual(state.written, previous.written) {
+		hull, old := state.written[0], Interval{Lo: unbounded, Hi: -unbounded}
+		for _, addresses := range state.written {
+			hull = hull.join(addresses)
+		}
+		for _, addresses := range previous.written {
+			old = old.join(addresses)
+		}
+		state.written = []Interval{hull.widen(old)}
+	}
+}
+
+// ValueAnalysis computes by abstract interpretation the range of every
+// stack value and heap cell before each instruction. Calls are followed
+// into their subroutines, and a ret returns to every call of the subroutine
+// it belongs to.
+type ValueAnalysis struct {
+	instructions []Instruction
+	labels       map[string]int
+	returns      map[int][]int
+	states       []*abstractState
+}
+
+func AnalyzeValues(instructions []Instruction) *ValueAnalysis {
+	analysis := &ValueAnalysis{
+		instructions: instructions,
+		labels:       labelTable(instructions),
+		returns:      map[int][]int{},
+		states:       make([]*abstractState, len(instructions)),
+	}
+	if len(instructions) == 0 {
+		return analysis
+	}
+
+	cfg := NewControlFlowGraph(instructions)
+	for pc, instruction := range instructions {
+		if _, ok := instruction.(EndSubroutine); ok {
+			if subroutine := cfg.BlockAt(pc).Subroutine; subroutine != "" {
+				for call, instruction := range instructions {
+					if c, ok := instruction.(CallSubroutine); ok && c.label == subroutine && call+1 < len(instructions) {
+						analysis.returns[pc] = append(analysis.returns[pc], call+1)
+					}
+				}
+			}
+		}
+	}
+
+	analysis.states[0] = &abstractState{cells: map[int]Interval{}, initialized: map[int]bool{}}
+	changes := map[int]int{}
+	worklist := []int{0}
+	for len(worklist) > 0 {
+		pc := worklist[0]
+		worklist = worklist[1:]
+
+		after, ok := analysis.transfer(pc, analysis.states[pc].copy())
+		if !ok {
+			continue
+		}
+		for _, next := range analysis.successors(pc) {
+			previous := analysis.states[next]
+			if previous == nil {
+				analysis.states[next] = after.copy()
+				worklist = append(worklist, next)
+				continue
+			}
+
+			joined := previous.join(after)
+			if reflect.DeepEqual(joined, previous) {
+				continue
+			}
+			// Loops go through a label, but a recursive subroutine also
+			// cycles through the return sites after its calls, so every
+			// instruction that keeps changing is widened.
+			if changes[next]++; changes[next] > widenAfter {
+				joined.widen(previous)
+			}
+			analysis.states[next] = joined
+			worklist = append(worklist, next)
+		}
+	}
+
+	return analysis
+}
+
+func (analysis *ValueAnalysis) successors(pc int) []int {
+	switch i := analysis.instructions[pc].(type) {
+	case CallSubroutine:
+		if target, ok := analysis.labels[i.label]; ok {
+			return []int{target}
+		}
+		return nil
+	case EndSubroutine:
+		return analysis.returns[pc]
+	default:
+		return successors(analysis.instructions, analysis.labels, pc)
+	}
+}
+
+// transfer returns the state after the instruction at pc, or false when it
+// always fails.
+func (analysis *ValueAnalysis) transfer(pc int, state *abstractState) (*abstractState, bool) {
+	instruction := analysis.instructions[pc]
+	switch i := instruction.(type) {
+	case Push:
+		state.push(singleton(i.value))
+	case Duplicate:
+		value := state.pop()
+		state.push(value)
+		state.push(value)
+	case Swap:
+		a, b := state.pop(), state.pop()
+		state.push(a)
+		state.push(b)
+	case Addition, Subtraction, Multiplication, Division, Modulo:
+		lhs, rhs := state.pop(), state.pop()
+		switch instruction.(type) {
+		case Division, Modulo:
+			if rhs == singleton(0) {
+				return state, false
+			}
+		}
+		state.push(arithmeticInterval(instruction, lhs, rhs))
+	case Store:
+		value, address := state.pop(), state.pop()
+		state.write(address, value)
+	case Retrieve:
+		value, ok := state.read(state.pop())
+		if !ok {
+			return state, false
+		}
+		state.push(value)
+	case Getc:
+		state.write(state.pop(), Interval{Lo: 0, Hi: 0x10FFFF})
+	case Getn:
+		state.write(state.pop(), topInterval)
+	default:
+		need, delta := stackEffect(instruction)
+		for n := 0; n < need; n++ {
+			state.pop()
+		}
+		for n := 0; n < need+delta; n++ {
+			state.push(topInterval)
+		}
+	}
+	return state, true
+}
+
+// Reachable reports whether the instruction at pc may run.
+func (analysis *ValueAnalysis) Reachable(pc int) bool {
+	return analysis.states[pc] != nil
+}
+
+// StackAt returns the ranges of the values on the stack before the
+// instruction at pc, top last, and whether the stack may hold more values
+// below them.
+func (analysis *ValueAnalysis) StackAt(pc int) ([]Interval, bool) {
+	state := analysis.states[pc]
+	if state == nil {
+		return nil, false
+	}
+	return append([]Interval{}, state.stack...), state.deep
+}
+
+// Operand returns the range of the value n places below the top of the stack
+// before the instruction at pc.
+func (analysis *ValueAnalysis) Operand(pc, n int) Interval {
+	if analysis.states[pc] == nil {
+		return topInterval
+	}
+	return analysis.states[pc].peek(n)
+}
+
+// Initialized reports whether the Retrieve at pc is proven to read an
+// address that was written on every path to it.
+func (analysis *ValueAnalysis) Initialized(pc int) bool {
+	state := analysis.states[pc]
+	if state == nil {
+		return true
+	}
+	address := state.peek(0)
+	return address.isSingleton() && state.initialized[address.Lo]
+}
+
+// MayBeWritten reports whether the Retrieve at pc may read an address that
+// was written on some path to it.
+func (analysis *ValueAnalysis) MayBeWritten(pc int) bool {
+	state := analysis.states[pc]
+	if state == nil {
+		return true
+	}
+	address := state.peek(0)
+	for _, addresses := range state.written {
+		if addresses.overlaps(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeapCells returns the ranges of heap addresses the program may read or
+// write, in increasing order.
+func (analysis *ValueAnalysis) HeapCells() []Interval {
+	var cells []Interval
+	for pc, instruction := range analysis.instructions {
+		var address Interval
+		switch instruction.(type) {
+		case Store:
+			address = analysis.Operand(pc, 1)
+		case Retrieve, Getc, Getn:
+			address = analysis.Operand(pc, 0)
+		default:
+			continue
+		}
+		if analysis.Reachable(pc) {
+			cells = addWritten(cells, address)
+		}
+	}
+	return cells
+}
+
+// validCodePoint reports whether every value in i is a Unicode code point,
+// and whether any of them is one that is not a surrogate. Intervals can not
+// leave out the surrogates, so they only make a value invalid when it is
+// certainly one of them.
+func validCodePoint(i Interval) (all bool, some bool) {
+	some = i.overlaps(Interval{Lo: 0, Hi: 0xD7FF}) || i.overlaps(Interval{Lo: 0xE000, Hi: 0x10FFFF})
+	return some && i.Lo >= 0 && i.Hi <= 0x10FFFF, some
+}
+
+// WriteReport writes the ranges of the stack values before every
+// instruction, top last, followed by the heap cells the program may touch.
+func (analysis *ValueAnalysis) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "pc\tinstruction\tstack")
+	for pc, instruction := range analysis.instructions {
+		stack, deep := analysis.StackAt(pc)
+		if !analysis.Reachable(pc) {
+			fmt.Fprintf(tw, "%d\t%s\tunreachable\n", pc, Mnemonic(instruction))
+			continue
+		}
+
+		values := make([]string, 0, len(stack)+1)
+		if deep {
+			values = append(values, "...")
+		}
+		for _, value := range stack {
+			values = append(values, value.String())
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", pc, Mnemonic(instruction), strings.Join(values, " "))
+	}
+
+	cells := make([]string, 0)
+	for _, addresses := range analysis.HeapCells() {
+		cells = append(cells, addresses.String())
+	}
+	fmt.Fprintf(tw, "\nheap cells: %s\n", strings.Join(cells, ", "))
+
+	return tw.Flush()
+}
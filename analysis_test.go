@@ -0,0 +1,128 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func analyzeSource(t *testing.T, source string) *ValueAnalysis {
+	instructions, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("can not assemble: %s", err.Error())
+	}
+	return AnalyzeValues(instructions)
+}
+
+func TestAnalyzeValuesArithmetic(t *testing.T) {
+	analysis := analyzeSource(t, `
+		push 0 getc
+		push 0 retrieve push 10 swap mod
+		push 3 push 0 retrieve sub
+		push 2 push 0 retrieve div
+		end`)
+
+	assert.Equal(t, Interval{Lo: 0, Hi: 0x10FFFF}, analysis.Operand(5, 1))
+	assert.Equal(t, Interval{Lo: 0, Hi: 9}, analysis.Operand(7, 0))
+	assert.Equal(t, Interval{Lo: -3, Hi: 0x10FFFF - 3}, analysis.Operand(11, 0))
+	assert.Equal(t, Interval{Lo: 0, Hi: 0x10FFFF / 2}, analysis.Operand(15, 0))
+}
+
+func TestAnalyzeValuesUnboundedModulo(t *testing.T) {
+	analysis := analyzeSource(t, "push 0 getn push 1 getn push 1 retrieve push 0 retrieve mod end")
+
+	assert.Equal(t, topInterval, analysis.Operand(9, 0))
+	assert.Equal(t, "[-inf, +inf]", analysis.Operand(9, 0).String())
+}
+
+func TestAnalyzeValuesLoopsAndCalls(t *testing.T) {
+	analysis := analyzeSource(t, `
+		push 0 push 10 store
+		label S push 0 retrieve jz T
+		push 0 push -1 push 0 retrieve add store jump S
+		label T push 5 call TT end
+		label TT dup putn ret
+		push 1`)
+
+	assert.Equal(t, Interval{Lo: -unbounded, Hi: 10}, analysis.Operand(6, 0))
+	stack, deep := analysis.StackAt(20)
+	assert.Equal(t, []Interval{{Lo: 5, Hi: 5}, {Lo: 5, Hi: 5}}, stack)
+	assert.False(t, deep)
+	assert.False(t, analysis.Reachable(22))
+	assert.Equal(t, []Interval{{Lo: 0, Hi: 0}}, analysis.HeapCells())
+}
+
+func TestAnalyzeValuesRecursion(t *testing.T) {
+	analysis := analyzeSource(t, `
+		push 5 call S end
+		label S dup jz T push 1 swap sub call S push 1 add ret
+		label T ret`)
+
+	assert.Equal(t, Interval{Lo: -unbounded, Hi: 5}, analysis.Operand(5, 0))
+	assert.Equal(t, topInterval, analysis.Operand(2, 0))
+}
+
+func TestAnalyzeValuesHeap(t *testing.T) {
+	analysis := analyzeSource(t, `
+		push 0 getn push 0 retrieve jz S
+		push 1 push 7 store
+		label S push 1 retrieve push 0 retrieve retrieve
+		push 0 retrieve push 5 store
+		end`)
+
+	assert.False(t, analysis.Initialized(10))
+	assert.True(t, analysis.MayBeWritten(10))
+	assert.True(t, analysis.Initialized(12))
+	assert.False(t, analysis.Initialized(13))
+	assert.Equal(t, Interval{Lo: 7, Hi: 7}, analysis.Operand(11, 0))
+	assert.Equal(t, []Interval{{Lo: -unbounded, Hi: unbounded}}, analysis.HeapCells())
+}
+
+func TestAnalyzeValuesWriteReport(t *testing.T) {
+	var out bytes.Buffer
+	analyzeSource(t, "push 1 push 2 store jump S push 3 label S end").WriteReport(&out)
+
+	assert.Equal(t, "pc  instruction  stack\n"+
+		"0   push 1       \n"+
+		"1   push 2       1\n"+
+		"2   store        1 2\n"+
+		"3   jump S       \n"+
+		"4   push 3       unreachable\n"+
+		"5   label S      \n"+
+		"6   end          \n"+
+		"\n"+
+		"heap cells: 1\n", out.String())
+}
+
+func TestLintUninitializedRetrieve(t *testing.T) {
+	issues := lintSource(t, `
+		push 0 getn push 0 retrieve jz S
+		push 1 push 7 store
+		label S push 1 retrieve push 0 retrieve retrieve
+		push 2 retrieve push 0 retrieve push 3 add retrieve
+		end`, "uninitialized-retrieve")
+
+	assert.Equal(t, []Issue{
+		{Rule: "uninitialized-retrieve", Severity: SeverityWarning, PC: 10, Message: "heap address 1 may be retrieved before it is stored"},
+		{Rule: "uninitialized-retrieve", Severity: SeverityWarning, PC: 15, Message: "heap address 2 may be retrieved before it is stored"},
+	}, issues)
+
+	issues = lintSource(t, "push 0 push 1 store push 0 getc push 0 retrieve push 10 add retrieve end", "uninitialized-retrieve")
+	assert.Equal(t, []Issue{
+		{Rule: "uninitialized-retrieve", Severity: SeverityWarning, PC: 9, Message: "heap addresses [10, 1114121] are retrieved before any of them is stored"},
+	}, issues)
+}
+
+func TestLintInvalidCodePoint(t *testing.T) {
+	issues := lintSource(t, `
+		push 0 getc push 0 retrieve putc
+		push 0 retrieve push -10 add putc
+		push 55296 putc
+		push 0 getn push 0 retrieve putc
+		end`, "invalid-code-point")
+
+	assert.Equal(t, []Issue{
+		{Rule: "invalid-code-point", Severity: SeverityWarning, PC: 9, Message: "putc may write [-10, 1114101], which includes values that are not Unicode code points"},
+		{Rule: "invalid-code-point", Severity: SeverityWarning, PC: 11, Message: "putc writes 55296, which is not a Unicode code point"},
+	}, issues)
+}
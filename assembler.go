@@ -0,0 +1,125 @@
+package whitespace_go
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Assemble translates the mnemonics printed by Mnemonic back into
+// instructions. Mnemonics are separated by whitespace, so several
+// instructions may share a line, and a ';' starts a comment that runs to the
+// end of the line. Labels are written with the letters S and T.
+//
+//	push 1 push 2 add putn ; prints 3
+//	label ST call T jz TS ret end
+func Assemble(source string) ([]Instruction, error) {
+	var fields []string
+	for _, line := range strings.Split(source, LF) {
+		if comment := strings.Index(line, ";"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields = append(fields, strings.Fields(line)...)
+	}
+
+	var instructions []Instruction
+	for i := 0; i < len(fields); i++ {
+		mnemonic := strings.ToLower(fields[i])
+
+		if instruction, ok := assembleNullary(mnemonic); ok {
+			instructions = append(instructions, instruction)
+			continue
+		}
+
+		if i+1 >= len(fields) {
+			return instructions, assembleError("expected operand after " + mnemonic)
+		}
+		i++
+		operand := fields[i]
+
+		if mnemonic == "push" {
+			n, err := strconv.Atoi(operand)
+			if err != nil {
+				return instructions, assembleError("invalid number " + operand)
+			}
+			instructions = append(instructions, Push{value: n})
+			continue
+		}
+
+		label, err := parseLabelName(operand)
+		if err != nil {
+			return instructions, err
+		}
+
+		switch mnemonic {
+		case "label":
+			instructions = append(instructions, MarkLabel{label: label})
+		case "call":
+			instructions = append(instructions, CallSubroutine{label: label})
+		case "jump":
+			instructions = append(instructions, JumpLabel{label: label})
+		case "jz":
+			instructions = append(instructions, JumpLabelWhenZero{label: label})
+		case "jn":
+			instructions = append(instructions, JumpLabelWhenNegative{label: label})
+		default:
+			return instructions, assembleError("unknown instruction " + fields[i-1])
+		}
+	}
+
+	return instructions, nil
+}
+
+func assembleNullary(mnemonic string) (Instruction, bool) {
+	switch mnemonic {
+	case "dup":
+		return Duplicate{}, true
+	case "swap":
+		return Swap{}, true
+	case "discard":
+		return Discard{}, true
+	case "add":
+		return Addition{}, true
+	case "sub":
+		return Subtraction{}, true
+	case "mul":
+		return Multiplication{}, true
+	case "div":
+		return Division{}, true
+	case "mod":
+		return Modulo{}, true
+	case "store":
+		return Store{}, true
+	case "retrieve":
+		return Retrieve{}, true
+	case "putc":
+		return Putc{}, true
+	case "putn":
+		return Putn{}, true
+	case "getc":
+		return Getc{}, true
+	case "getn":
+		return Getn{}, true
+	case "ret":
+		return EndSubroutine{}, true
+	case "end":
+		return EndProgram{}, true
+	default:
+		return nil, false
+	}
+}
+
+// parseLabelName is the inverse of LabelName.
+func parseLabelName(name string) (string, error) {
+	var label strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		switch r {
+		case 'S':
+			label.WriteString(SPACE)
+		case 'T':
+			label.WriteString(TAB)
+		default:
+			return "", assembleError("invalid label " + name + ", labels are written with S and T")
+		}
+	}
+	return label.String(), nil
+}
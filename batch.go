@@ -0,0 +1,250 @@
+package whitespace_go
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchOptions configures RunBatch. Workers is how many inputs run at a
+// time, the number of CPUs when 0. Timeout and Limits bound every run.
+type BatchOptions struct {
+	Workers int
+	Timeout time.Duration
+	Limits  Limits
+}
+
+// BatchStatus is how a run of a batch went.
+type BatchStatus int
+
+const (
+	// BatchPassed is a program that ended with the expected output, or with
+	// any output when there is no .expected file.
+	BatchPassed BatchStatus = iota
+	// BatchFailed is a program that ended with another output than expected.
+	BatchFailed
+	// BatchError is a program that failed, or an input that could not be
+	// read or an output that could not be written.
+	BatchError
+)
+
+func (status BatchStatus) String() string {
+	switch status {
+	case BatchPassed:
+		return "pass"
+	case BatchFailed:
+		return "fail"
+	default:
+		return "error"
+	}
+}
+
+// BatchRun is the outcome of running a program on one input file. Output
+// is the file the output was written to, and Expected the file it was
+// compared with, empty when there is none. Message explains a failure or
+// error, and Err is the error of a BatchError.
+type BatchRun struct {
+	Input    string
+	Output   string
+	Expected string
+	Status   BatchStatus
+	Message  string
+	Err      error
+	Steps    int
+	Duration time.Duration
+}
+
+// RunBatch runs program once for every input file, several at a time. The
+// output of input.in is written to input.out and, when input.expected
+// exists, compared with it. The runs are returned in the order of inputs.
+func RunBatch(ctx context.Context, program *Program, inputs []string, options BatchOptions) []BatchRun {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	runs := make([]BatchRun, len(inputs))
+	indexes := make(chan int)
+	var wait sync.WaitGroup
+	for n := 0; n < workers; n++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := range indexes {
+				runs[index] = runInput(ctx, program, inputs[index], options)
+			}
+		}()
+	}
+
+	for index := range inputs {
+		indexes <- index
+	}
+	close(indexes)
+	wait.Wait()
+	return runs
+}
+
+func runInput(ctx context.Context, program *Program, input string, options BatchOptions) (run BatchRun) {
+	base := strings.TrimSuffix(input, filepath.Ext(input))
+	run = BatchRun{Input: input, Output: base + ".out"}
+	fail := func(err error) BatchRun {
+		run.Status, run.Message, run.Err = BatchError, err.Error(), err
+		return run
+	}
+	// A panic on one input must not take the other runs down with it.
+	defer func() {
+		if r := recover(); r != nil {
+			run = fail(fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if run.Output == input {
+		return fail(fmt.Errorf("%s would be overwritten by its output", input))
+	}
+	file, err := os.Open(input)
+	if err != nil {
+		return fail(err)
+	}
+	defer file.Close()
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, errRun := program.Run(ctx, WithInput(file), WithLimits(options.Limits))
+	run.Duration = time.Since(start)
+	run.Steps = result.Steps
+
+	if err := ioutil.WriteFile(run.Output, []byte(result.Output), 0644); err != nil {
+		return fail(err)
+	}
+	if errRun != nil {
+		return fail(errRun)
+	}
+
+	expected, err := ioutil.ReadFile(base + ".expected")
+	if os.IsNotExist(err) {
+		return run
+	}
+	run.Expected = base + ".expected"
+	if err != nil {
+		return fail(err)
+	}
+	if difference := firstDifference(string(expected), result.Output); difference != "" {
+		run.Status = BatchFailed
+		run.Message = fmt.Sprintf("output differs from %s %s", run.Expected, difference)
+	}
+	return run
+}
+
+// firstDifference describes the first line where got differs from want, and
+// returns "" when they are the same.
+func firstDifference(want string, got string) string {
+	if want == got {
+		return ""
+	}
+
+	wantLines, gotLines := outputLines(want), outputLines(got)
+	line := 0
+	for line < len(wantLines) && line < len(gotLines) && wantLines[line] == gotLines[line] {
+		line++
+	}
+
+	wantLine, gotLine := "end of output", "end of output"
+	if line < len(wantLines) {
+		wantLine = fmt.Sprintf("%q", wantLines[line])
+	}
+	if line < len(gotLines) {
+		gotLine = fmt.Sprintf("%q", gotLines[line])
+	}
+	return fmt.Sprintf("at line %d: got %s, want %s", line+1, gotLine, wantLine)
+}
+
+// outputLines splits output after every LF, without the empty line
+// following a last LF.
+func outputLines(output string) []string {
+	lines := strings.SplitAfter(output, LF)
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes runs as a JUnit XML test suite called name, with a test
+// case for every input.
+func WriteJUnit(w io.Writer, name string, runs []BatchRun) error {
+	suite := junitTestSuite{Name: name, Tests: len(runs)}
+	var total time.Duration
+	for _, run := range runs {
+		testCase := junitTestCase{
+			Name:      run.Input,
+			Classname: name,
+			Time:      junitSeconds(run.Duration),
+			SystemOut: fmt.Sprintf("steps: %d", run.Steps),
+		}
+		switch run.Status {
+		case BatchFailed:
+			suite.Failures++
+			testCase.Failure = &junitProblem{Message: run.Message}
+		case BatchError:
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: run.Message}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		total += run.Duration
+	}
+	suite.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, LF)
+	return err
+}
+
+func junitSeconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,141 @@
+package whitespace_go
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeBatchFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "batch")
+	assert.Nil(t, err)
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := writeBatchFiles(t, map[string]string{
+		"a.in":       "3\n",
+		"a.expected": "6",
+		"b.in":       "10\n",
+		"b.expected": "56",
+		"c.in":       "100\n",
+		"d.in":       "x\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var inputs []string
+	for _, name := range []string{"a.in", "b.in", "c.in", "d.in", "e.in"} {
+		inputs = append(inputs, filepath.Join(dir, name))
+	}
+	runs := RunBatch(context.Background(), compileAssembly(t, sumProgram), inputs, BatchOptions{Workers: 2})
+
+	var statuses []BatchStatus
+	for _, run := range runs {
+		statuses = append(statuses, run.Status)
+	}
+	assert.Equal(t, []BatchStatus{BatchPassed, BatchFailed, BatchPassed, BatchError, BatchError}, statuses)
+
+	assert.Equal(t, filepath.Join(dir, "a.out"), runs[0].Output)
+	assert.Equal(t, filepath.Join(dir, "a.expected"), runs[0].Expected)
+	assert.Equal(t, 70, runs[0].Steps)
+	assert.Equal(t, "output differs from "+runs[1].Expected+` at line 1: got "55", want "56"`, runs[1].Message)
+	assert.Equal(t, "", runs[2].Expected)
+	assert.EqualError(t, runs[3].Err, "Runtime error: input character is not numeric")
+	assert.True(t, os.IsNotExist(runs[4].Err))
+
+	output, err := ioutil.ReadFile(filepath.Join(dir, "c.out"))
+	assert.Nil(t, err)
+	assert.Equal(t, "5050", string(output))
+}
+
+type panicOnNegative struct{}
+
+func (p panicOnNegative) Execute(executor *Executor) error {
+	n, err := executor.Pop()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		panic("negative input")
+	}
+	return executor.Push(n)
+}
+
+func TestRunBatchRecoversPanics(t *testing.T) {
+	dir := writeBatchFiles(t, map[string]string{"a.in": "1\n", "b.in": "-1\n", "c.in": "2\n"})
+	defer os.RemoveAll(dir)
+
+	instructions, err := Assemble("push 0 getn push 0 retrieve")
+	assert.Nil(t, err)
+	program := NewProgram(append(instructions, panicOnNegative{}, Putn{}, EndProgram{}))
+
+	var inputs []string
+	for _, name := range []string{"a.in", "b.in", "c.in"} {
+		inputs = append(inputs, filepath.Join(dir, name))
+	}
+	runs := RunBatch(context.Background(), program, inputs, BatchOptions{Workers: 2})
+
+	assert.Equal(t, BatchPassed, runs[0].Status)
+	assert.Equal(t, BatchError, runs[1].Status)
+	assert.EqualError(t, runs[1].Err, "panic: negative input")
+	assert.Equal(t, BatchPassed, runs[2].Status)
+
+	output, err := ioutil.ReadFile(filepath.Join(dir, "c.out"))
+	assert.Nil(t, err)
+	assert.Equal(t, "2", string(output))
+}
+
+func TestRunBatchLimits(t *testing.T) {
+	dir := writeBatchFiles(t, map[string]string{"a.in": "1000000\n", "b.out": ""})
+	defer os.RemoveAll(dir)
+
+	inputs := []string{filepath.Join(dir, "a.in"), filepath.Join(dir, "b.out")}
+	runs := RunBatch(context.Background(), compileAssembly(t, sumProgram), inputs, BatchOptions{Timeout: time.Minute, Limits: Limits{MaxSteps: 1000}})
+
+	assert.Equal(t, StepLimit, runs[0].Err.(*LimitError).Limit)
+	assert.Equal(t, 1000, runs[0].Steps)
+	assert.EqualError(t, runs[1].Err, inputs[1]+" would be overwritten by its output")
+}
+
+func TestFirstDifference(t *testing.T) {
+	assert.Equal(t, "", firstDifference("a\nb\n", "a\nb\n"))
+	assert.Equal(t, `at line 2: got "c\n", want "b\n"`, firstDifference("a\nb\n", "a\nc\n"))
+	assert.Equal(t, `at line 2: got end of output, want "b"`, firstDifference("a\nb", "a\n"))
+	assert.Equal(t, `at line 1: got "a", want "a\n"`, firstDifference("a\n", "a"))
+}
+
+func TestWriteJUnit(t *testing.T) {
+	runs := []BatchRun{
+		{Input: "a.in", Status: BatchPassed, Steps: 3, Duration: 1500 * time.Microsecond},
+		{Input: "b.in", Status: BatchFailed, Message: `output differs from b.expected at line 1: got "1", want "2"`, Steps: 4},
+		{Input: "c.in", Status: BatchError, Message: "Runtime error: stack is epmty", Steps: 1},
+	}
+
+	var builder strings.Builder
+	assert.Nil(t, WriteJUnit(&builder, "prog.ws", runs))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="prog.ws" tests="3" failures="1" errors="1" time="0.002">
+    <testcase name="a.in" classname="prog.ws" time="0.002">
+      <system-out>steps: 3</system-out>
+    </testcase>
+    <testcase name="b.in" classname="prog.ws" time="0.000">
+      <failure message="output differs from b.expected at line 1: got &#34;1&#34;, want &#34;2&#34;"></failure>
+      <system-out>steps: 4</system-out>
+    </testcase>
+    <testcase name="c.in" classname="prog.ws" time="0.000">
+      <error message="Runtime error: stack is epmty"></error>
+      <system-out>steps: 1</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, builder.String())
+}
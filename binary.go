@@ -0,0 +1,369 @@
+package whitespace_go
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"reflect"
+)
+
+// The compiled format, .wsc, is a parsed and linked program:
+//
+//	magic        "WSC\x00"
+//	version      byte
+//	flags        byte, binaryHasSourceMap | binaryHasSource
+//	instructions uvarint count, then per instruction its opcode byte, the
+//	             index of its command, followed by a zigzag varint for push
+//	             and a uvarint label index for label instructions
+//	labels       uvarint count, then per label a uvarint number of tokens,
+//	             the tokens as bits (tab is 1) packed from the high bit, and
+//	             the uvarint pc of its MarkLabel plus one, 0 if undefined
+//	source map   a string for the file name, then per instruction uvarints
+//	             for its offset, end, line and column
+//	source       a string
+//	checksum     CRC-32 (IEEE) of everything before it, big endian
+//
+// Strings are a uvarint length followed by their bytes.
+const (
+	binaryMagic   = "WSC\x00"
+	binaryVersion = 1
+
+	binaryHasSourceMap = 1 << 0
+	binaryHasSource    = 1 << 1
+)
+
+// BinaryExtension is the extension of compiled programs.
+const BinaryExtension = ".wsc"
+
+// SourceMap locates the instructions of a compiled program in the source it
+// was built from. Source is the source itself, kept with debug info.
+type SourceMap struct {
+	Filename  string
+	Positions []Position
+	Source    string
+}
+
+type binaryWriter struct {
+	bytes.Buffer
+}
+
+func (w *binaryWriter) uvarint(n uint64) {
+	var buffer [binary.MaxVarintLen64]byte
+	w.Write(buffer[:binary.PutUvarint(buffer[:], n)])
+}
+
+func (w *binaryWriter) varint(n int64) {
+	var buffer [binary.MaxVarintLen64]byte
+	w.Write(buffer[:binary.PutVarint(buffer[:], n)])
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+// WriteBinary writes instructions in the compiled format. The source map is
+// left out when sourceMap is nil, and the source when it is empty.
+func WriteBinary(w io.Writer, instructions []Instruction, sourceMap *SourceMap) error {
+	out := &binaryWriter{}
+	out.WriteString(binaryMagic)
+	out.WriteByte(binaryVersion)
+
+	var flags byte
+	if sourceMap != nil {
+		flags |= binaryHasSourceMap
+		if sourceMap.Source != "" {
+			flags |= binaryHasSource
+		}
+	}
+	out.WriteByte(flags)
+
+	var labels []string
+	indexes := map[string]int{}
+	out.uvarint(uint64(len(instructions)))
+	for _, instruction := range instructions {
+		command := commandOf(instruction)
+		out.WriteByte(opcodeOf(command))
+
+		switch command.parameter {
+		case numberParameter:
+			out.varint(int64(instruction.(Push).value))
+		case labelParameter:
+			label := instructionLabel(instruction)
+			if _, ok := indexes[label]; !ok {
+				indexes[label] = len(labels)
+				labels = append(labels, label)
+			}
+			out.uvarint(uint64(indexes[label]))
+		}
+	}
+
+	targets := labelTable(instructions)
+	out.uvarint(uint64(len(labels)))
+	for _, label := range labels {
+		out.uvarint(uint64(len(label)))
+		out.Write(packLabel(label))
+		if pc, ok := targets[label]; ok {
+			out.uvarint(uint64(pc) + 1)
+		} else {
+			out.uvarint(0)
+		}
+	}
+
+	if sourceMap != nil {
+		if len(sourceMap.Positions) != len(instructions) {
+			return errors.New("the source map does not match the instructions")
+		}
+		out.string(sourceMap.Filename)
+		for _, position := range sourceMap.Positions {
+			out.uvarint(uint64(position.Offset))
+			out.uvarint(uint64(position.End))
+			out.uvarint(uint64(position.Line))
+			out.uvarint(uint64(position.Column))
+		}
+		if sourceMap.Source != "" {
+			out.string(sourceMap.Source)
+		}
+	}
+
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(out.Bytes()))
+	out.Write(checksum[:])
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// opcodeOf returns the opcode of command, its index in commands.
+func opcodeOf(command command) byte {
+	for opcode := range commands {
+		if commands[opcode] == command {
+			return byte(opcode)
+		}
+	}
+	panic("unknown command " + command.name)
+}
+
+func packLabel(label string) []byte {
+	packed := make([]byte, (len(label)+7)/8)
+	for n := 0; n < len(label); n++ {
+		if label[n:n+1] == TAB {
+			packed[n/8] |= 0x80 >> uint(n%8)
+		}
+	}
+	return packed
+}
+
+func unpackLabel(packed []byte, length int) string {
+	label := make([]byte, length)
+	for n := range label {
+		if packed[n/8]&(0x80>>uint(n%8)) != 0 {
+			label[n] = TAB[0]
+		} else {
+			label[n] = SPACE[0]
+		}
+	}
+	return string(label)
+}
+
+type binaryReader struct {
+	*bufio.Reader
+	size int
+}
+
+func (r *binaryReader) uvarint() (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+// count reads a number of items, each at least one byte long.
+func (r *binaryReader) count() (int, error) {
+	n, err := r.uvarint()
+	if err == nil && n > uint64(r.size) {
+		err = errors.New("count is larger than the file")
+	}
+	return int(n), err
+}
+
+func (r *binaryReader) bytes(n int) ([]byte, error) {
+	buffer := make([]byte, n)
+	_, err := io.ReadFull(r, buffer)
+	return buffer, err
+}
+
+func (r *binaryReader) string() (string, error) {
+	n, err := r.count()
+	if err != nil {
+		return "", err
+	}
+	s, err := r.bytes(n)
+	return string(s), err
+}
+
+// end makes sure nothing follows what was read.
+func (r *binaryReader) end() error {
+	if _, err := r.ReadByte(); err != io.EOF {
+		return errors.New("unexpected data after the program")
+	}
+	return nil
+}
+
+// ReadBinary reads a program written by WriteBinary. The source map is nil
+// when the program was built without one.
+func ReadBinary(r io.Reader) ([]Instruction, *SourceMap, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instructions, sourceMap, err := decodeBinary(data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errors.New("the program is truncated")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid compiled program: %s", err.Error())
+	}
+	return instructions, sourceMap, nil
+}
+
+func decodeBinary(data []byte) ([]Instruction, *SourceMap, error) {
+	header := len(binaryMagic) + 2
+	if len(data) < header+4 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return nil, nil, errors.New("not a compiled program")
+	}
+	if data[len(binaryMagic)] != binaryVersion {
+		return nil, nil, fmt.Errorf("unsupported version %d", data[len(binaryMagic)])
+	}
+	body, checksum := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(checksum) {
+		return nil, nil, errors.New("checksum mismatch")
+	}
+	flags := data[len(binaryMagic)+1]
+	in := &binaryReader{Reader: bufio.NewReader(bytes.NewReader(body[header:])), size: len(body)}
+
+	type reference struct {
+		pc    int
+		name  string
+		label int
+	}
+	var references []reference
+
+	count, err := in.count()
+	if err != nil {
+		return nil, nil, err
+	}
+	instructions := make([]Instruction, count)
+	for pc := range instructions {
+		opcode, err := in.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		if int(opcode) >= len(commands) {
+			return nil, nil, fmt.Errorf("unknown opcode %d", opcode)
+		}
+
+		command := commands[opcode]
+		switch command.parameter {
+		case numberParameter:
+			n, err := binary.ReadVarint(in)
+			if err != nil {
+				return nil, nil, err
+			}
+			instructions[pc] = Push{value: int(n)}
+		case labelParameter:
+			label, err := in.uvarint()
+			if err != nil {
+				return nil, nil, err
+			}
+			references = append(references, reference{pc: pc, name: command.name, label: int(label)})
+		default:
+			instructions[pc], _ = assembleNullary(command.name)
+		}
+	}
+
+	count, err = in.count()
+	if err != nil {
+		return nil, nil, err
+	}
+	labels := make([]string, count)
+	targets := map[string]int{}
+	for n := range labels {
+		length, err := in.uvarint()
+		if err != nil {
+			return nil, nil, err
+		}
+		if length == 0 {
+			return nil, nil, errors.New("label is empty")
+		}
+		if length > uint64(in.size)*8 {
+			return nil, nil, errors.New("label is larger than the file")
+		}
+		packed, err := in.bytes(int(length+7) / 8)
+		if err != nil {
+			return nil, nil, err
+		}
+		target, err := in.uvarint()
+		if err != nil {
+			return nil, nil, err
+		}
+		labels[n] = unpackLabel(packed, int(length))
+		if target > 0 {
+			targets[labels[n]] = int(target - 1)
+		}
+	}
+
+	for _, reference := range references {
+		if reference.label >= len(labels) {
+			return nil, nil, fmt.Errorf("unknown label %d", reference.label)
+		}
+		instructions[reference.pc] = labelInstruction(reference.name, labels[reference.label])
+	}
+	if !reflect.DeepEqual(labelTable(instructions), targets) {
+		return nil, nil, errors.New("the label table does not match the instructions")
+	}
+
+	if flags&binaryHasSourceMap == 0 {
+		return instructions, nil, in.end()
+	}
+
+	sourceMap := &SourceMap{Positions: make([]Position, len(instructions))}
+	if sourceMap.Filename, err = in.string(); err != nil {
+		return nil, nil, err
+	}
+	for pc := range sourceMap.Positions {
+		var fields [4]uint64
+		for n := range fields {
+			if fields[n], err = in.uvarint(); err != nil {
+				return nil, nil, err
+			}
+		}
+		sourceMap.Positions[pc] = Position{Offset: int(fields[0]), End: int(fields[1]), Line: int(fields[2]), Column: int(fields[3])}
+	}
+	if flags&binaryHasSource != 0 {
+		if sourceMap.Source, err = in.string(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return instructions, sourceMap, in.end()
+}
+
+// labelInstruction returns the instruction of the command called name with
+// label as its operand.
+func labelInstruction(name string, label string) Instruction {
+	switch name {
+	case "label":
+		return MarkLabel{label: label}
+	case "call":
+		return CallSubroutine{label: label}
+	case "jump":
+		return JumpLabel{label: label}
+	case "jz":
+		return JumpLabelWhenZero{label: label}
+	default:
+		return JumpLabelWhenNegative{label: label}
+	}
+}
@@ -0,0 +1,107 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+var binarySource = SPACE + SPACE + TAB + TAB + SPACE + TAB + LF +
+	LF + SPACE + SPACE + TAB + SPACE + TAB + TAB + SPACE + SPACE + TAB + TAB + SPACE + LF +
+	SPACE + LF + SPACE +
+	TAB + LF + SPACE + TAB +
+	LF + TAB + TAB + TAB + SPACE + TAB + TAB + SPACE + SPACE + TAB + TAB + SPACE + LF +
+	LF + LF + LF
+
+func compileBinary(t *testing.T, sourceMap bool, source string) []byte {
+	parser := NewParser("binary.ws", binarySource)
+	assert.Nil(t, parser.ParseAll())
+
+	var buffer bytes.Buffer
+	if sourceMap {
+		assert.Nil(t, WriteBinary(&buffer, parser.Instructions, &SourceMap{Filename: "binary.ws", Positions: parser.Positions, Source: source}))
+	} else {
+		assert.Nil(t, WriteBinary(&buffer, parser.Instructions, nil))
+	}
+	return buffer.Bytes()
+}
+
+// resign replaces the checksum of a compiled program after it was altered.
+func resign(data []byte) []byte {
+	body := data[:len(data)-4]
+	signed := append([]byte{}, body...)
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(body))
+	return append(signed, checksum[:]...)
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	parser := NewParser("binary.ws", binarySource)
+	assert.Nil(t, parser.ParseAll())
+
+	instructions, sourceMap, err := ReadBinary(bytes.NewReader(compileBinary(t, false, "")))
+	assert.Nil(t, err)
+	assert.Equal(t, parser.Instructions, instructions)
+	assert.Nil(t, sourceMap)
+
+	instructions, sourceMap, err = ReadBinary(bytes.NewReader(compileBinary(t, true, "")))
+	assert.Nil(t, err)
+	assert.Equal(t, parser.Instructions, instructions)
+	assert.Equal(t, &SourceMap{Filename: "binary.ws", Positions: parser.Positions}, sourceMap)
+
+	_, sourceMap, err = ReadBinary(bytes.NewReader(compileBinary(t, true, binarySource)))
+	assert.Nil(t, err)
+	assert.Equal(t, binarySource, sourceMap.Source)
+}
+
+func TestBinaryNegativeAndLargeNumbers(t *testing.T) {
+	program := []Instruction{Push{value: -1 << 40}, Push{value: 1<<62 - 1}, EndProgram{}}
+	var buffer bytes.Buffer
+	assert.Nil(t, WriteBinary(&buffer, program, nil))
+
+	instructions, _, err := ReadBinary(&buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, program, instructions)
+}
+
+func TestBinaryRejectsCorruptPrograms(t *testing.T) {
+	data := compileBinary(t, false, "")
+
+	_, _, err := ReadBinary(strings.NewReader("#!/usr/bin/env ws\n"))
+	assert.EqualError(t, err, "invalid compiled program: not a compiled program")
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(binaryMagic)+3] ^= 1
+	_, _, err = ReadBinary(bytes.NewReader(corrupt))
+	assert.EqualError(t, err, "invalid compiled program: checksum mismatch")
+
+	corrupt = append([]byte{}, data...)
+	corrupt[len(binaryMagic)] = 2
+	_, _, err = ReadBinary(bytes.NewReader(resign(corrupt)))
+	assert.EqualError(t, err, "invalid compiled program: unsupported version 2")
+
+	_, _, err = ReadBinary(bytes.NewReader(resign(data[:len(data)-8])))
+	assert.EqualError(t, err, "invalid compiled program: the program is truncated")
+
+	corrupt = append(append([]byte{}, data[:len(data)-4]...), 0, 0, 0, 0, 0)
+	_, _, err = ReadBinary(bytes.NewReader(resign(corrupt)))
+	assert.EqualError(t, err, "invalid compiled program: unexpected data after the program")
+}
+
+func TestBinaryRejectsWrongLabelTable(t *testing.T) {
+	data := compileBinary(t, false, "")
+
+	// The last byte before the checksum is the target of the only label.
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-5]++
+	_, _, err := ReadBinary(bytes.NewReader(resign(corrupt)))
+	assert.EqualError(t, err, "invalid compiled program: the label table does not match the instructions")
+
+	// The only label is 9 bits long, packed into two bytes before its target.
+	corrupt = append(append([]byte{}, data[:len(data)-8]...), 0, data[len(data)-5], 0, 0, 0, 0)
+	_, _, err = ReadBinary(bytes.NewReader(resign(corrupt)))
+	assert.EqualError(t, err, "invalid compiled program: label is empty")
+}
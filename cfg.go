@@ -0,0 +1,292 @@
+package whitespace_go
+
+// labelTable maps every label to the index of the MarkLabel defining it.
+// Like the linear scans done by the jump instructions, the first definition
+// of a label wins.
+func labelTable(instructions []Instruction) map[string]int {
+	labels := map[string]int{}
+	for pc, instruction := range instructions {
+		if m, ok := instruction.(MarkLabel); ok {
+			if _, defined := labels[m.label]; !defined {
+				labels[m.label] = pc
+			}
+		}
+	}
+	return labels
+}
+
+// jumpLabel returns the label an instruction transfers control to.
+func jumpLabel(instruction Instruction) (string, bool) {
+	switch i := instruction.(type) {
+	case CallSubroutine:
+		return i.label, true
+	case JumpLabel:
+		return i.label, true
+	case JumpLabelWhenZero:
+		return i.label, true
+	case JumpLabelWhenNegative:
+		return i.label, true
+	default:
+		return "", false
+	}
+}
+
+// successors returns the instructions that may run after the one at pc
+// within the same subroutine. A call continues after the call instruction
+// and its callee is not included; EndSubroutine and EndProgram have no
+// successors, and neither does falling off the end of the program. Jumps to
+// undefined labels have no successor for the jump itself.
+func successors(instructions []Instruction, labels map[string]int, pc int) []int {
+	var next []int
+	fallThrough := func() {
+		if pc+1 < len(instructions) {
+			next = append(next, pc+1)
+		}
+	}
+	jump := func(label string) {
+		if target, ok := labels[label]; ok {
+			next = append(next, target)
+		}
+	}
+
+	switch i := instructions[pc].(type) {
+	case JumpLabel:
+		jump(i.label)
+	case JumpLabelWhenZero:
+		fallThrough()
+		jump(i.label)
+	case JumpLabelWhenNegative:
+		fallThrough()
+		jump(i.label)
+	case EndSubroutine, EndProgram:
+	default:
+		fallThrough()
+	}
+
+	return next
+}
+
+// reachable marks the instructions that can run when the program starts at
+// the first instruction, entering every subroutine that a reachable call
+// names and assuming every call returns.
+func reachable(instructions []Instruction, labels map[string]int) []bool {
+	seen := make([]bool, len(instructions))
+	var worklist []int
+	visit := func(pc int) {
+		if pc < len(instructions) && !seen[pc] {
+			seen[pc] = true
+			worklist = append(worklist, pc)
+		}
+	}
+
+	visit(0)
+	for len(worklist) > 0 {
+		pc := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		if call, ok := instructions[pc].(CallSubroutine); ok {
+			if target, defined := labels[call.label]; defined {
+				visit(target)
+			}
+		}
+		for _, next := range successors(instructions, labels, pc) {
+			visit(next)
+		}
+	}
+
+	return seen
+}
+
+type EdgeKind int
+
+const (
+	EdgeFallthrough EdgeKind = iota
+	EdgeJump
+	EdgeZero
+	EdgeNegative
+	EdgeReturn
+)
+
+func (kind EdgeKind) String() string {
+	switch kind {
+	case EdgeJump:
+		return "jump"
+	case EdgeZero:
+		return "zero"
+	case EdgeNegative:
+		return "negative"
+	case EdgeReturn:
+		return "return"
+	default:
+		return "fallthrough"
+	}
+}
+
+type Edge struct {
+	Kind EdgeKind
+	To   int
+}
+
+// BasicBlock is a run of instructions, instructions[Start:End], that is
+// only entered at Start and only left after End-1. Subroutine is the label
+// of the subroutine the block belongs to, or "" for the main program, and
+// Reachable is false when the block can never run.
+type BasicBlock struct {
+	ID         int
+	Start      int
+	End        int
+	Edges      []Edge
+	Subroutine string
+	Reachable  bool
+}
+
+// ControlFlowGraph splits a program into basic blocks at every MarkLabel
+// and after every jump, call, ret and end. Subroutines lists the main
+// program ("") and every reachable subroutine in the order they are found,
+// and Calls records the subroutines each of them calls.
+type ControlFlowGraph struct {
+	Instructions []Instruction
+	Blocks       []*BasicBlock
+	Subroutines  []string
+	Calls        map[string][]string
+	labels       map[string]int
+	blockOf      []int
+}
+
+func NewControlFlowGraph(instructions []Instruction) *ControlFlowGraph {
+	cfg := &ControlFlowGraph{
+		Instructions: instructions,
+		Calls:        map[string][]string{},
+		labels:       labelTable(instructions),
+		blockOf:      make([]int, len(instructions)),
+	}
+
+	start := 0
+	for pc, instruction := range instructions {
+		if _, ok := instruction.(MarkLabel); ok && pc > start {
+			cfg.addBlock(start, pc)
+			start = pc
+		}
+
+		switch instruction.(type) {
+		case JumpLabel, JumpLabelWhenZero, JumpLabelWhenNegative, CallSubroutine, EndSubroutine, EndProgram:
+			cfg.addBlock(start, pc+1)
+			start = pc + 1
+		}
+	}
+	if start < len(instructions) {
+		cfg.addBlock(start, len(instructions))
+	}
+
+	for _, block := range cfg.Blocks {
+		cfg.connect(block)
+	}
+	cfg.assignSubroutines()
+
+	return cfg
+}
+
+func (cfg *ControlFlowGraph) addBlock(start, end int) {
+	id := len(cfg.Blocks)
+	cfg.Blocks = append(cfg.Blocks, &BasicBlock{ID: id, Start: start, End: end})
+	for pc := start; pc < end; pc++ {
+		cfg.blockOf[pc] = id
+	}
+}
+
+// BlockAt returns the block containing the instruction at pc.
+func (cfg *ControlFlowGraph) BlockAt(pc int) *BasicBlock {
+	return cfg.Blocks[cfg.blockOf[pc]]
+}
+
+func (cfg *ControlFlowGraph) connect(block *BasicBlock) {
+	last := block.End - 1
+	edge := func(kind EdgeKind, pc int) {
+		block.Edges = append(block.Edges, Edge{Kind: kind, To: cfg.blockOf[pc]})
+	}
+	jump := func(kind EdgeKind, label string) {
+		if target, ok := cfg.labels[label]; ok {
+			edge(kind, target)
+		}
+	}
+	fallThrough := func(kind EdgeKind) {
+		if block.End < len(cfg.Instructions) {
+			edge(kind, block.End)
+		}
+	}
+
+	switch i := cfg.Instructions[last].(type) {
+	case JumpLabel:
+		jump(EdgeJump, i.label)
+	case JumpLabelWhenZero:
+		jump(EdgeZero, i.label)
+		fallThrough(EdgeFallthrough)
+	case JumpLabelWhenNegative:
+		jump(EdgeNegative, i.label)
+		fallThrough(EdgeFallthrough)
+	case CallSubroutine:
+		fallThrough(EdgeReturn)
+	case EndSubroutine, EndProgram:
+	default:
+		fallThrough(EdgeFallthrough)
+	}
+}
+
+// assignSubroutines walks the blocks of the main program and of every
+// called subroutine without following calls. A block shared by several
+// subroutines belongs to the first one that reaches it.
+func (cfg *ControlFlowGraph) assignSubroutines() {
+	if len(cfg.Blocks) == 0 {
+		return
+	}
+
+	type entry struct {
+		name  string
+		block int
+	}
+	entries := []entry{{"", 0}}
+	known := map[string]bool{"": true}
+
+	for n := 0; n < len(entries); n++ {
+		owner := entries[n].name
+		cfg.Subroutines = append(cfg.Subroutines, owner)
+		worklist := []int{entries[n].block}
+		seen := map[int]bool{entries[n].block: true}
+
+		for len(worklist) > 0 {
+			block := cfg.Blocks[worklist[len(worklist)-1]]
+			worklist = worklist[:len(worklist)-1]
+
+			if !block.Reachable {
+				block.Reachable = true
+				block.Subroutine = owner
+			}
+
+			if call, ok := cfg.Instructions[block.End-1].(CallSubroutine); ok {
+				if target, defined := cfg.labels[call.label]; defined {
+					cfg.addCall(owner, call.label)
+					if !known[call.label] {
+						known[call.label] = true
+						entries = append(entries, entry{call.label, cfg.blockOf[target]})
+					}
+				}
+			}
+
+			for _, edge := range block.Edges {
+				if !seen[edge.To] {
+					seen[edge.To] = true
+					worklist = append(worklist, edge.To)
+				}
+			}
+		}
+	}
+}
+
+func (cfg *ControlFlowGraph) addCall(caller, callee string) {
+	for _, name := range cfg.Calls[caller] {
+		if name == callee {
+			return
+		}
+	}
+	cfg.Calls[caller] = append(cfg.Calls[caller], callee)
+}
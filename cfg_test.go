@@ -0,0 +1,64 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func cfgProgram(t *testing.T) *ControlFlowGraph {
+	instructions, err := Assemble(`
+		push 3 call T end
+		label T dup jz S push 1 sub call T
+		label S discard ret
+		push 9`)
+	if err != nil {
+		t.Fatalf("can not assemble: %s", err.Error())
+	}
+	return NewControlFlowGraph(instructions)
+}
+
+func TestControlFlowGraphBlocks(t *testing.T) {
+	cfg := cfgProgram(t)
+
+	assert.Equal(t, []*BasicBlock{
+		{ID: 0, Start: 0, End: 2, Edges: []Edge{{Kind: EdgeReturn, To: 1}}, Subroutine: "", Reachable: true},
+		{ID: 1, Start: 2, End: 3, Subroutine: "", Reachable: true},
+		{ID: 2, Start: 3, End: 6, Edges: []Edge{{Kind: EdgeZero, To: 4}, {Kind: EdgeFallthrough, To: 3}}, Subroutine: TAB, Reachable: true},
+		{ID: 3, Start: 6, End: 9, Edges: []Edge{{Kind: EdgeReturn, To: 4}}, Subroutine: TAB, Reachable: true},
+		{ID: 4, Start: 9, End: 12, Subroutine: TAB, Reachable: true},
+		{ID: 5, Start: 12, End: 13},
+	}, cfg.Blocks)
+
+	assert.Equal(t, []string{"", TAB}, cfg.Subroutines)
+	assert.Equal(t, map[string][]string{"": {TAB}, TAB: {TAB}}, cfg.Calls)
+	assert.Equal(t, 3, cfg.BlockAt(8).ID)
+}
+
+func TestControlFlowGraphWriteDOT(t *testing.T) {
+	var out bytes.Buffer
+	cfgProgram(t).WriteDOT(&out)
+
+	assert.Contains(t, out.String(), "subgraph cluster_1 {\n    label=\"T\";\n    b2 [label=\"3: label T\\l4: dup\\l5: jz S\\l\"];\n")
+	assert.Contains(t, out.String(), "  b2 -> b4 [label=\"zero\"];\n  b2 -> b3 [label=\"fallthrough\"];\n")
+	assert.Contains(t, out.String(), "  b0 -> b1 [label=\"return\" style=dashed];\n")
+	assert.Contains(t, out.String(), "  }\n  b5 [label=\"12: push 9\\l\"];\n")
+}
+
+func TestControlFlowGraphWriteMermaid(t *testing.T) {
+	var out bytes.Buffer
+	cfgProgram(t).WriteMermaid(&out)
+
+	assert.Contains(t, out.String(), "  subgraph s0[\"main\"]\n    b0[\"0: push 3<br/>1: call T\"]\n")
+	assert.Contains(t, out.String(), "  b3 -.->|return| b4\n")
+}
+
+func TestControlFlowGraphWriteCallGraph(t *testing.T) {
+	var dot, mermaid bytes.Buffer
+	cfg := cfgProgram(t)
+	cfg.WriteCallGraphDOT(&dot)
+	cfg.WriteCallGraphMermaid(&mermaid)
+
+	assert.Equal(t, "digraph calls {\n  \"main\";\n  \"T\";\n  \"main\" -> \"T\";\n  \"T\" -> \"T\";\n}\n", dot.String())
+	assert.Equal(t, "flowchart LR\n  s0[\"main\"]\n  s1[\"T\"]\n  s0 --> s1\n  s1 --> s1\n", mermaid.String())
+}
@@ -0,0 +1,367 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (severity Severity) String() string {
+	if severity == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue is a defect found in a program without running it. PC is the
+// instruction the issue is reported at and Rule names the check that found
+// it.
+type Issue struct {
+	Rule     string
+	Severity Severity
+	PC       int
+	Message  string
+}
+
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].PC != issues[j].PC {
+			return issues[i].PC < issues[j].PC
+		}
+		return issues[i].Rule < issues[j].Rule
+	})
+}
+
+// unbounded stands for an infinite stack depth in a depthRange.
+const unbounded = 1 << 40
+
+// depthRange is the interval of stack depths possible at an instruction.
+type depthRange struct {
+	lo, hi int
+}
+
+func exactDepth(n int) depthRange {
+	return depthRange{lo: n, hi: n}
+}
+
+func saturate(n int) int {
+	if n >= unbounded {
+		return unbounded
+	}
+	if n <= -unbounded {
+		return -unbounded
+	}
+	return n
+}
+
+func (r depthRange) add(delta depthRange) depthRange {
+	return depthRange{lo: saturate(r.lo + delta.lo), hi: saturate(r.hi + delta.hi)}
+}
+
+func (r depthRange) join(other depthRange) depthRange {
+	if other.lo < r.lo {
+		r.lo = other.lo
+	}
+	if other.hi > r.hi {
+		r.hi = other.hi
+	}
+	return r
+}
+
+func (r depthRange) String() string {
+	format := func(n int) string {
+		switch n {
+		case unbounded:
+			return "+inf"
+		case -unbounded:
+			return "-inf"
+		default:
+			return fmt.Sprintf("%+d", n)
+		}
+	}
+	if r.lo == r.hi {
+		return format(r.lo)
+	}
+	return format(r.lo) + ".." + format(r.hi)
+}
+
+// stackEffect returns how many values an instruction needs on the stack and
+// how much it changes the depth by.
+func stackEffect(instruction Instruction) (need int, delta int) {
+	switch instruction.(type) {
+	case Push:
+		return 0, 1
+	case Duplicate:
+		return 1, 1
+	case Swap:
+		return 2, 0
+	case Discard:
+		return 1, -1
+	case Addition, Subtraction, Multiplication, Division, Modulo:
+		return 2, -1
+	case Store:
+		return 2, -2
+	case Retrieve:
+		return 1, 0
+	case Putc, Putn, Getc, Getn:
+		return 1, -1
+	case JumpLabelWhenZero, JumpLabelWhenNegative:
+		return 1, -1
+	default:
+		return 0, 0
+	}
+}
+
+// stackSummary is the stack behaviour of a subroutine: how many values it
+// needs on entry and how it changes the depth by the time it returns.
+type stackSummary struct {
+	need    int
+	effect  depthRange
+	returns bool
+}
+
+// stackAnalysis follows the stack depth through the instructions reachable
+// from one entry point, without leaving the subroutine the entry belongs to.
+type stackAnalysis struct {
+	instructions []Instruction
+	labels       map[string]int
+	summaries    map[string]*stackSummary
+	absolute     bool
+	depths       map[int]depthRange
+	changes      map[int]int
+	issues       map[string]Issue
+	summary      stackSummary
+}
+
+// widenAfter is how many times the depth at one instruction may change
+// before it is assumed to keep changing in a loop.
+const widenAfter = 16
+
+func (analysis *stackAnalysis) report(rule string, severity Severity, pc int, message string) {
+	key := fmt.Sprintf("%s:%d", rule, pc)
+	if _, ok := analysis.issues[key]; !ok {
+		analysis.issues[key] = Issue{Rule: rule, Severity: severity, PC: pc, Message: message}
+	}
+}
+
+func (analysis *stackAnalysis) run(entry int) {
+	analysis.depths = map[int]depthRange{entry: exactDepth(0)}
+	analysis.changes = map[int]int{}
+	analysis.issues = map[string]Issue{}
+
+	worklist := []int{entry}
+	for len(worklist) > 0 {
+		pc := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		after, ok := analysis.transfer(pc, analysis.depths[pc], false)
+		if !ok {
+			continue
+		}
+
+		for _, next := range successors(analysis.instructions, analysis.labels, pc) {
+			if analysis.merge(next, after) {
+				worklist = append(worklist, next)
+			}
+		}
+	}
+
+	// Underflows are only reported once the depths are final.
+	for pc, depth := range analysis.depths {
+		analysis.transfer(pc, depth, true)
+	}
+}
+
+// merge joins depth into the depths known at pc and reports whether that
+// added anything new. Every loop passes through the MarkLabel it jumps back
+// to, so the depth is only widened there.
+func (analysis *stackAnalysis) merge(pc int, depth depthRange) bool {
+	previous, seen := analysis.depths[pc]
+	if !seen {
+		analysis.depths[pc] = depth
+		return true
+	}
+
+	joined := previous.join(depth)
+	if joined == previous {
+		return false
+	}
+
+	if _, ok := analysis.instructions[pc].(MarkLabel); ok {
+		analysis.changes[pc]++
+	}
+	if analysis.changes[pc] > widenAfter {
+		if joined.hi > previous.hi {
+			joined.hi = unbounded
+			analysis.report("unbounded-stack", SeverityWarning, pc, "the stack grows without bound in this loop")
+		}
+		if joined.lo < previous.lo {
+			joined.lo = -unbounded
+			analysis.report("unbounded-stack", SeverityWarning, pc, "the stack shrinks without bound in this loop")
+		}
+	}
+
+	analysis.depths[pc] = joined
+	return true
+}
+
+// transfer returns the depth after the instruction at pc, or false when no
+// execution continues past it. Underflows are only reported when report is
+// set.
+func (analysis *stackAnalysis) transfer(pc int, depth depthRange, report bool) (depthRange, bool) {
+	instruction := analysis.instructions[pc]
+	need, delta := stackEffect(instruction)
+	effect := exactDepth(delta)
+	what := Mnemonic(instruction)
+	ok := true
+
+	switch i := instruction.(type) {
+	case CallSubroutine:
+		summary, defined := analysis.summaries[i.label]
+		if !defined || !summary.returns {
+			return depth, false
+		}
+		need, effect = summary.need, summary.effect
+		what = fmt.Sprintf("subroutine %s", LabelName(i.label))
+	case EndSubroutine:
+		if analysis.summary.returns {
+			analysis.summary.effect = analysis.summary.effect.join(depth)
+		} else {
+			analysis.summary.effect = depth
+			analysis.summary.returns = true
+		}
+		return depth, false
+	}
+
+	if need > 0 {
+		if analysis.absolute {
+			if depth, ok = analysis.checkUnderflow(pc, depth, need, what, report); !ok {
+				return depth, false
+			}
+		} else if required := saturate(need - depth.lo); required > analysis.summary.need {
+			analysis.summary.need = required
+		}
+	}
+
+	return depth.add(effect), true
+}
+
+// checkUnderflow reports, when report is set, whether depth may be below
+// need and returns the depths that survive the instruction, or false when
+// none do.
+func (analysis *stackAnalysis) checkUnderflow(pc int, depth depthRange, need int, what string, report bool) (depthRange, bool) {
+	if need >= unbounded {
+		if report {
+			analysis.report("underflow", SeverityWarning, pc, fmt.Sprintf("%s may pop more values than the stack holds", what))
+		}
+		return depth, true
+	}
+
+	if depth.hi < need {
+		if report {
+			analysis.report("underflow", SeverityError, pc, fmt.Sprintf("%s needs %d values but the stack holds at most %d", what, need, depth.hi))
+		}
+		return depth, false
+	}
+
+	if depth.lo < need {
+		if report {
+			held := "may be empty"
+			if depth.lo > 0 {
+				held = fmt.Sprintf("may hold only %d", depth.lo)
+			}
+			analysis.report("underflow", SeverityWarning, pc, fmt.Sprintf("%s needs %d values but the stack %s", what, need, held))
+		}
+		depth.lo = need
+	}
+
+	return depth, true
+}
+
+// CheckStack computes the possible stack depth at every instruction and
+// reports stack underflows that will or may happen, loops that grow or
+// shrink the stack without bound and subroutines that do not always leave
+// the stack with the same depth.
+func CheckStack(instructions []Instruction) []Issue {
+	labels := labelTable(instructions)
+	summaries := map[string]*stackSummary{}
+
+	var called []string
+	for _, instruction := range instructions {
+		if call, ok := instruction.(CallSubroutine); ok {
+			if _, defined := labels[call.label]; defined && summaries[call.label] == nil {
+				summaries[call.label] = &stackSummary{}
+				called = append(called, call.label)
+			}
+		}
+	}
+
+	// Summaries are computed together until none of them changes, since
+	// subroutines may call each other recursively.
+	analyses := map[string]*stackAnalysis{}
+	changes := map[string]int{}
+	for changed := true; changed; {
+		changed = false
+		for _, label := range called {
+			analysis := &stackAnalysis{instructions: instructions, labels: labels, summaries: summaries}
+			analysis.run(labels[label])
+			analyses[label] = analysis
+
+			previous := summaries[label]
+			summary := analysis.summary
+			if summary == *previous {
+				continue
+			}
+
+			changes[label]++
+			if changes[label] > widenAfter && previous.returns {
+				summary.effect = summary.effect.join(previous.effect)
+				if summary.effect.hi > previous.effect.hi {
+					summary.effect.hi = unbounded
+				}
+				if summary.effect.lo < previous.effect.lo {
+					summary.effect.lo = -unbounded
+				}
+				if summary.need > previous.need {
+					summary.need = unbounded
+				}
+			}
+			if summary != *previous {
+				*previous = summary
+				changed = true
+			}
+		}
+	}
+
+	main := &stackAnalysis{instructions: instructions, labels: labels, summaries: summaries, absolute: true}
+	if len(instructions) > 0 {
+		main.run(0)
+	}
+
+	var issues []Issue
+	for _, issue := range main.issues {
+		issues = append(issues, issue)
+	}
+	for _, label := range called {
+		for _, issue := range analyses[label].issues {
+			if _, duplicate := main.issues[fmt.Sprintf("%s:%d", issue.Rule, issue.PC)]; !duplicate {
+				issues = append(issues, issue)
+			}
+		}
+
+		summary := summaries[label]
+		if summary.returns && summary.effect.lo != summary.effect.hi {
+			message := fmt.Sprintf("subroutine %s returns with an inconsistent stack effect of %s values", LabelName(label), summary.effect)
+			issues = append(issues, Issue{Rule: "inconsistent-subroutine", Severity: SeverityWarning, PC: labels[label], Message: message})
+		}
+	}
+
+	sortIssues(issues)
+	return issues
+}
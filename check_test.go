@@ -0,0 +1,77 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func checkSource(t *testing.T, source string) []Issue {
+	instructions, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("can not assemble %q: %s", source, err.Error())
+	}
+	return CheckStack(instructions)
+}
+
+func TestCheckStackBalancedProgram(t *testing.T) {
+	issues := checkSource(t, `
+		push 10
+		label S dup putn push 1 sub dup jz T jump S
+		label T discard end`)
+
+	assert.Empty(t, issues)
+}
+
+func TestCheckStackGuaranteedUnderflow(t *testing.T) {
+	issues := checkSource(t, "push 1 add end")
+
+	assert.Equal(t, []Issue{
+		{Rule: "underflow", Severity: SeverityError, PC: 1, Message: "add needs 2 values but the stack holds at most 1"},
+	}, issues)
+}
+
+func TestCheckStackPossibleUnderflow(t *testing.T) {
+	issues := checkSource(t, "push 0 jz S push 1 label S push 2 add end")
+
+	assert.Equal(t, []Issue{
+		{Rule: "underflow", Severity: SeverityWarning, PC: 5, Message: "add needs 2 values but the stack may hold only 1"},
+	}, issues)
+}
+
+func TestCheckStackUnboundedLoop(t *testing.T) {
+	issues := checkSource(t, "label S push 1 jump S")
+
+	assert.Equal(t, []Issue{
+		{Rule: "unbounded-stack", Severity: SeverityWarning, PC: 0, Message: "the stack grows without bound in this loop"},
+	}, issues)
+}
+
+func TestCheckStackSubroutineNeedsArguments(t *testing.T) {
+	issues := checkSource(t, `
+		push 1 call T push 1 push 2 call T discard end
+		label T add ret`)
+
+	assert.Equal(t, []Issue{
+		{Rule: "underflow", Severity: SeverityError, PC: 1, Message: "subroutine T needs 2 values but the stack holds at most 1"},
+	}, issues)
+}
+
+func TestCheckStackInconsistentSubroutine(t *testing.T) {
+	issues := checkSource(t, `
+		push 0 call T end
+		label T jz S push 1 push 2 ret
+		label S ret`)
+
+	assert.Equal(t, []Issue{
+		{Rule: "inconsistent-subroutine", Severity: SeverityWarning, PC: 3, Message: "subroutine T returns with an inconsistent stack effect of -1..+1 values"},
+	}, issues)
+}
+
+func TestCheckStackRecursiveSubroutine(t *testing.T) {
+	issues := checkSource(t, `
+		push 3 call T discard end
+		label T dup jz S push 1 sub call T
+		label S ret`)
+
+	assert.Empty(t, issues)
+}
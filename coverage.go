@@ -0,0 +1,255 @@
+package whitespace_go
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const coverageMode = "mode: count"
+
+// Coverage is a Hook that records how many times every instruction was
+// executed and, for JumpLabelWhenZero and JumpLabelWhenNegative, how many
+// times the jump was taken or fell through.
+type Coverage struct {
+	instructions []Instruction
+	hash         string
+	counts       []int
+	taken        []int
+	notTaken     []int
+}
+
+func NewCoverage(instructions []Instruction) *Coverage {
+	return &Coverage{
+		instructions: instructions,
+		hash:         programHash(instructions),
+		counts:       make([]int, len(instructions)),
+		taken:        make([]int, len(instructions)),
+		notTaken:     make([]int, len(instructions)),
+	}
+}
+
+func (coverage *Coverage) Before(executor *Executor, pc int) error {
+	return nil
+}
+
+func (coverage *Coverage) After(executor *Executor, pc int) error {
+	coverage.counts[pc]++
+
+	if isConditionalJump(coverage.instructions[pc]) {
+		if executor.programCounter != pc {
+			coverage.taken[pc]++
+		} else {
+			coverage.notTaken[pc]++
+		}
+	}
+
+	return nil
+}
+
+func isConditionalJump(instruction Instruction) bool {
+	switch instruction.(type) {
+	case JumpLabelWhenZero, JumpLabelWhenNegative:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarkLabel is only executed when reached by falling through, so it is not
+// counted as an executable instruction.
+func isExecutable(instruction Instruction) bool {
+	_, ok := instruction.(MarkLabel)
+	return !ok
+}
+
+// Merge adds the counts recorded by other, which must have been collected
+// for the same program.
+func (coverage *Coverage) Merge(other *Coverage) error {
+	if coverage.hash != other.hash {
+		return errors.New("coverage was recorded for a different program")
+	}
+
+	for pc := range coverage.counts {
+		coverage.counts[pc] += other.counts[pc]
+		coverage.taken[pc] += other.taken[pc]
+		coverage.notTaken[pc] += other.notTaken[pc]
+	}
+
+	return nil
+}
+
+// WriteProfile writes the raw counts so that they can be merged with the
+// counts of later runs by ReadCoverage.
+func (coverage *Coverage) WriteProfile(w io.Writer) error {
+	fmt.Fprintln(w, coverageMode)
+	fmt.Fprintf(w, "program: %s\n", coverage.hash)
+	for pc := range coverage.counts {
+		if _, err := fmt.Fprintf(w, "%d %d %d %d\n", pc, coverage.counts[pc], coverage.taken[pc], coverage.notTaken[pc]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadCoverage reads a profile written by WriteProfile for instructions.
+func ReadCoverage(r io.Reader, instructions []Instruction) (*Coverage, error) {
+	coverage := NewCoverage(instructions)
+	scanner := bufio.NewScanner(r)
+
+	if !scanner.Scan() || scanner.Text() != coverageMode {
+		return nil, errors.New("invalid coverage profile: missing mode line")
+	}
+
+	if !scanner.Scan() || scanner.Text() != "program: "+coverage.hash {
+		return nil, errors.New("coverage was recorded for a different program")
+	}
+
+	for scanner.Scan() {
+		var pc, count, taken, notTaken int
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d %d %d", &pc, &count, &taken, &notTaken); err != nil {
+			return nil, fmt.Errorf("invalid coverage profile: %s", err.Error())
+		}
+		if pc < 0 || pc >= len(instructions) {
+			return nil, fmt.Errorf("invalid coverage profile: instruction %d out of range", pc)
+		}
+		coverage.counts[pc] += count
+		coverage.taken[pc] += taken
+		coverage.notTaken[pc] += notTaken
+	}
+
+	return coverage, scanner.Err()
+}
+
+func (coverage *Coverage) summary() (covered, total, branchesCovered, branches int) {
+	for pc, instruction := range coverage.instructions {
+		if !isExecutable(instruction) {
+			continue
+		}
+		total++
+		if coverage.counts[pc] > 0 {
+			covered++
+		}
+		if isConditionalJump(instruction) {
+			branches += 2
+			if coverage.taken[pc] > 0 {
+				branchesCovered++
+			}
+			if coverage.notTaken[pc] > 0 {
+				branchesCovered++
+			}
+		}
+	}
+	return
+}
+
+func coveragePercent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+// WriteReport writes the disassembly annotated with execution counts.
+// Instructions that never ran are marked with ##### and conditional jumps
+// show how often each branch direction was taken.
+func (coverage *Coverage) WriteReport(w io.Writer, positions []Position) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "count\tpc\tposition\tinstruction\tbranches")
+	for pc, instruction := range coverage.instructions {
+		count := "-"
+		if isExecutable(instruction) {
+			count = "#####"
+			if coverage.counts[pc] > 0 {
+				count = fmt.Sprint(coverage.counts[pc])
+			}
+		}
+
+		branches := ""
+		if isConditionalJump(instruction) {
+			branches = fmt.Sprintf("taken %d, not taken %d", coverage.taken[pc], coverage.notTaken[pc])
+		}
+
+		location := fmt.Sprintf("pc %d", pc)
+		if position, ok := positionAt(positions, pc); ok {
+			location = position.String()
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", count, pc, location, Mnemonic(instruction), branches)
+	}
+
+	covered, total, branchesCovered, branches := coverage.summary()
+	fmt.Fprintf(tw, "\ninstructions: %d/%d (%.1f%%)\n", covered, total, coveragePercent(covered, total))
+	fmt.Fprintf(tw, "branches: %d/%d (%.1f%%)\n", branchesCovered, branches, coveragePercent(branchesCovered, branches))
+
+	return tw.Flush()
+}
+
+// WriteLCOV writes the coverage in the LCOV tracefile format keyed by the
+// source line each instruction starts on, or by the instruction number from
+// 1 when there are no positions. A line shared by several instructions
+// reports the highest count among them.
+func (coverage *Coverage) WriteLCOV(w io.Writer, filename string, positions []Position) error {
+	lineCounts := map[int]int{}
+	var branchLines []string
+	branchesFound, branchesHit := 0, 0
+
+	for pc, instruction := range coverage.instructions {
+		if !isExecutable(instruction) {
+			continue
+		}
+
+		line := pc + 1
+		if position, ok := positionAt(positions, pc); ok {
+			line = position.Line
+		}
+		if count, ok := lineCounts[line]; !ok || coverage.counts[pc] > count {
+			lineCounts[line] = coverage.counts[pc]
+		}
+
+		if isConditionalJump(instruction) {
+			for branch, taken := range []int{coverage.taken[pc], coverage.notTaken[pc]} {
+				hits := "-"
+				if coverage.counts[pc] > 0 {
+					hits = fmt.Sprint(taken)
+				}
+				branchLines = append(branchLines, fmt.Sprintf("BRDA:%d,%d,%d,%s", line, pc, branch, hits))
+				branchesFound++
+				if taken > 0 {
+					branchesHit++
+				}
+			}
+		}
+	}
+
+	sortedLines := make([]int, 0, len(lineCounts))
+	for line := range lineCounts {
+		sortedLines = append(sortedLines, line)
+	}
+	sort.Ints(sortedLines)
+
+	var builder strings.Builder
+	builder.WriteString("TN:\n")
+	fmt.Fprintf(&builder, "SF:%s\n", filename)
+	for _, branchLine := range branchLines {
+		builder.WriteString(branchLine + "\n")
+	}
+	fmt.Fprintf(&builder, "BRF:%d\nBRH:%d\n", branchesFound, branchesHit)
+
+	linesHit := 0
+	for _, line := range sortedLines {
+		fmt.Fprintf(&builder, "DA:%d,%d\n", line, lineCounts[line])
+		if lineCounts[line] > 0 {
+			linesHit++
+		}
+	}
+	fmt.Fprintf(&builder, "LF:%d\nLH:%d\n", len(sortedLines), linesHit)
+	builder.WriteString("end_of_record\n")
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
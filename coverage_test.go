@@ -0,0 +1,111 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func coveredBranchProgram() []Instruction {
+	return []Instruction{
+		Push{value: 0},
+		JumpLabelWhenZero{label: TAB},
+		Push{value: 1},
+		MarkLabel{label: TAB},
+		EndProgram{},
+	}
+}
+
+func TestCoverageRecordsBranches(t *testing.T) {
+	instructions := coveredBranchProgram()
+	coverage := NewCoverage(instructions)
+	executor := &Executor{instructions: instructions}
+	executor.AddHook(coverage)
+	executor.Run()
+
+	assert.Equal(t, []int{1, 1, 0, 0, 1}, coverage.counts)
+	assert.Equal(t, 1, coverage.taken[1])
+	assert.Equal(t, 0, coverage.notTaken[1])
+
+	covered, total, branchesCovered, branches := coverage.summary()
+	assert.Equal(t, 3, covered)
+	assert.Equal(t, 4, total)
+	assert.Equal(t, 1, branchesCovered)
+	assert.Equal(t, 2, branches)
+}
+
+func TestCoverageProfileRoundTrip(t *testing.T) {
+	instructions := coveredBranchProgram()
+	coverage := NewCoverage(instructions)
+	coverage.counts = []int{1, 1, 1, 0, 1}
+	coverage.notTaken[1] = 1
+
+	var profile bytes.Buffer
+	coverage.WriteProfile(&profile)
+
+	recorded, err := ReadCoverage(&profile, instructions)
+	assert.Nil(t, err)
+
+	merged := NewCoverage(instructions)
+	merged.taken[1] = 1
+	merged.counts = []int{1, 1, 0, 0, 1}
+	err = merged.Merge(recorded)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 2, 1, 0, 2}, merged.counts)
+	assert.Equal(t, 1, merged.taken[1])
+	assert.Equal(t, 1, merged.notTaken[1])
+}
+
+func TestCoverageRejectsOtherProgram(t *testing.T) {
+	coverage := NewCoverage(coveredBranchProgram())
+
+	var profile bytes.Buffer
+	coverage.WriteProfile(&profile)
+
+	_, err := ReadCoverage(&profile, []Instruction{EndProgram{}})
+	assert.NotNil(t, err)
+}
+
+func TestCoverageWriteLCOV(t *testing.T) {
+	parser := NewParser("branch.ws", SPACE+SPACE+SPACE+SPACE+LF+LF+TAB+SPACE+TAB+LF+SPACE+SPACE+SPACE+TAB+LF+LF+SPACE+SPACE+TAB+LF+LF+LF+LF)
+	err := parser.ParseAll()
+	assert.Nil(t, err)
+	lines := make([]int, len(parser.Positions))
+	for pc, position := range parser.Positions {
+		lines[pc] = position.Line
+	}
+	assert.Equal(t, []int{1, 2, 4, 5, 7}, lines)
+
+	coverage := NewCoverage(parser.Instructions)
+	executor := &Executor{instructions: parser.Instructions}
+	executor.AddHook(coverage)
+	executor.Run()
+
+	var lcov bytes.Buffer
+	coverage.WriteLCOV(&lcov, "branch.ws", parser.Positions)
+
+	assert.Equal(t, "TN:\nSF:branch.ws\n"+
+		"BRDA:2,1,0,1\nBRDA:2,1,1,0\nBRF:2\nBRH:1\n"+
+		"DA:1,1\nDA:2,1\nDA:4,0\nDA:7,1\nLF:4\nLH:3\n"+
+		"end_of_record\n", lcov.String())
+}
+
+func TestCoverageWithoutPositions(t *testing.T) {
+	instructions := coveredBranchProgram()
+	coverage := NewCoverage(instructions)
+	executor := &Executor{instructions: instructions}
+	executor.AddHook(coverage)
+	executor.Run()
+
+	var report bytes.Buffer
+	assert.Nil(t, coverage.WriteReport(&report, nil))
+	assert.Contains(t, report.String(), "#####  2   pc 2      push 1")
+
+	var lcov bytes.Buffer
+	assert.Nil(t, coverage.WriteLCOV(&lcov, "branch.wsc", nil))
+	assert.Equal(t, "TN:\nSF:branch.wsc\n"+
+		"BRDA:2,1,0,1\nBRDA:2,1,1,0\nBRF:2\nBRH:1\n"+
+		"DA:1,1\nDA:2,1\nDA:3,0\nDA:5,1\nLF:4\nLH:3\n"+
+		"end_of_record\n", lcov.String())
+}
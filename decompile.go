@@ -0,0 +1,480 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// expression is a value computed by the program. Reads from the heap and
+// from values pushed before a block started are always bound to a temporary
+// first, so expressions have no side effects and can be moved freely.
+type expression interface {
+	String() string
+}
+
+type constantExpression struct {
+	value int
+}
+
+func (e constantExpression) String() string {
+	return fmt.Sprint(e.value)
+}
+
+type temporaryExpression struct {
+	name string
+}
+
+func (e temporaryExpression) String() string {
+	return e.name
+}
+
+type binaryExpression struct {
+	operator string
+	lhs, rhs expression
+}
+
+func (e binaryExpression) String() string {
+	return operand(e.lhs) + " " + e.operator + " " + operand(e.rhs)
+}
+
+func operand(e expression) string {
+	if _, ok := e.(binaryExpression); ok {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// condition is the test of a conditional jump, which is taken when value is
+// zero or, for negative, when value is below zero.
+type condition struct {
+	value    expression
+	negative bool
+}
+
+func (c condition) String() string {
+	if c.negative {
+		return c.value.String() + " < 0"
+	}
+	return c.value.String() + " == 0"
+}
+
+func (c condition) negated() string {
+	if c.negative {
+		return c.value.String() + " >= 0"
+	}
+	return c.value.String() + " != 0"
+}
+
+type statementKind int
+
+const (
+	statementSimple statementKind = iota
+	statementLabel
+	statementGoto
+	statementConditionalGoto
+	statementIf
+	statementWhile
+	statementDoWhile
+	statementLoop
+)
+
+// statement is a line of pseudocode. Label and Goto statements name their
+// target in label; If, While and DoWhile use cond, body and orElse.
+type statement struct {
+	kind   statementKind
+	text   string
+	label  string
+	cond   condition
+	body   []statement
+	orElse []statement
+}
+
+// lifter turns the stack operations of a basic block into statements over
+// expressions, keeping the values the block pushes on a symbolic stack.
+type lifter struct {
+	temporaries int
+	stack       []expression
+	statements  []statement
+}
+
+func (l *lifter) emit(format string, args ...interface{}) {
+	l.statements = append(l.statements, statement{kind: statementSimple, text: fmt.Sprintf(format, args...)})
+}
+
+func (l *lifter) temporary(format string, args ...interface{}) expression {
+	name := fmt.Sprintf("t%d", l.temporaries)
+	l.temporaries++
+	l.emit("%s = %s", name, fmt.Sprintf(format, args...))
+	return temporaryExpression{name: name}
+}
+
+func (l *lifter) push(e expression) {
+	l.stack = append(l.stack, e)
+}
+
+func (l *lifter) pop() expression {
+	if len(l.stack) == 0 {
+		return l.temporary("pop()")
+	}
+	e := l.stack[len(l.stack)-1]
+	l.stack = l.stack[:len(l.stack)-1]
+	return e
+}
+
+// flush pushes the values still on the symbolic stack onto the real stack,
+// bottom first.
+func (l *lifter) flush() {
+	for _, e := range l.stack {
+		l.emit("push(%s)", e)
+	}
+	l.stack = nil
+}
+
+var binaryOperators = map[string]string{
+	"add": "+",
+	"sub": "-",
+	"mul": "*",
+	"div": "/",
+	"mod": "%",
+}
+
+func (l *lifter) lift(instruction Instruction) {
+	switch i := instruction.(type) {
+	case Push:
+		l.push(constantExpression{value: i.value})
+	case Duplicate:
+		e := l.pop()
+		if _, ok := e.(binaryExpression); ok {
+			e = l.temporary("%s", e)
+		}
+		l.push(e)
+		l.push(e)
+	case Swap:
+		a, b := l.pop(), l.pop()
+		l.push(a)
+		l.push(b)
+	case Discard:
+		if len(l.stack) == 0 {
+			l.emit("pop()")
+		} else {
+			l.pop()
+		}
+	case Addition, Subtraction, Multiplication, Division, Modulo:
+		lhs, rhs := l.pop(), l.pop()
+		l.push(binaryExpression{operator: binaryOperators[Mnemonic(instruction)], lhs: lhs, rhs: rhs})
+	case Store:
+		value, address := l.pop(), l.pop()
+		l.emit("mem[%s] = %s", address, value)
+	case Retrieve:
+		l.push(l.temporary("mem[%s]", l.pop()))
+	case Putc, Putn:
+		l.emit("%s(%s)", Mnemonic(instruction), l.pop())
+	case Getc, Getn:
+		l.emit("mem[%s] = %s()", l.pop(), Mnemonic(instruction))
+	}
+}
+
+// Decompiler reconstructs structured pseudocode from a program: one
+// function for the main program and one per subroutine, with the stack
+// operations turned into expressions and the jumps into if/else and loops
+// where their shape allows it.
+type Decompiler struct {
+	cfg *ControlFlowGraph
+}
+
+func NewDecompiler(instructions []Instruction) *Decompiler {
+	return &Decompiler{cfg: NewControlFlowGraph(instructions)}
+}
+
+func functionName(subroutine string) string {
+	if subroutine == "" {
+		return mainFunction
+	}
+	return "sub_" + LabelName(subroutine)
+}
+
+// blockName names a block that is jumped to: by its label when it starts
+// with the first definition of one, by its index otherwise.
+func (decompiler *Decompiler) blockName(block *BasicBlock) string {
+	if m, ok := decompiler.cfg.Instructions[block.Start].(MarkLabel); ok && decompiler.cfg.labels[m.label] == block.Start {
+		return "L_" + LabelName(m.label)
+	}
+	return fmt.Sprintf("b%d", block.ID)
+}
+
+func (decompiler *Decompiler) labelTarget(label string) string {
+	target, ok := decompiler.cfg.labels[label]
+	if !ok {
+		return "L_" + LabelName(label) + " /* undefined */"
+	}
+	return decompiler.blockName(decompiler.cfg.BlockAt(target))
+}
+
+func (decompiler *Decompiler) function(subroutine string) []statement {
+	var blocks []*BasicBlock
+	for _, block := range decompiler.cfg.Blocks {
+		if block.Reachable && block.Subroutine == subroutine {
+			blocks = append(blocks, block)
+		}
+	}
+
+	l := &lifter{}
+	for n, block := range blocks {
+		l.statements = append(l.statements, statement{kind: statementLabel, label: decompiler.blockName(block)})
+
+		for pc := block.Start; pc < block.End; pc++ {
+			l.lift(decompiler.cfg.Instructions[pc])
+		}
+
+		var next *BasicBlock
+		if n+1 < len(blocks) {
+			next = blocks[n+1]
+		}
+		continueAt := func() {
+			if block.End < len(decompiler.cfg.Instructions) {
+				following := decompiler.cfg.BlockAt(block.End)
+				if following != next {
+					l.statements = append(l.statements, statement{kind: statementGoto, label: decompiler.blockName(following)})
+				}
+			}
+		}
+
+		switch i := decompiler.cfg.Instructions[block.End-1].(type) {
+		case JumpLabel:
+			l.flush()
+			l.statements = append(l.statements, statement{kind: statementGoto, label: decompiler.labelTarget(i.label)})
+		case JumpLabelWhenZero, JumpLabelWhenNegative:
+			label, _ := jumpLabel(i)
+			_, negative := i.(JumpLabelWhenNegative)
+			cond := condition{value: l.pop(), negative: negative}
+			l.flush()
+			l.statements = append(l.statements, statement{kind: statementConditionalGoto, label: decompiler.labelTarget(label), cond: cond})
+			continueAt()
+		case CallSubroutine:
+			l.flush()
+			if _, ok := decompiler.cfg.labels[i.label]; ok {
+				l.emit("%s()", functionName(i.label))
+			} else {
+				l.emit("%s() /* undefined */", functionName(i.label))
+			}
+			continueAt()
+		case EndSubroutine:
+			l.flush()
+			l.emit("return")
+		case EndProgram:
+			l.flush()
+			l.emit("exit")
+		default:
+			l.flush()
+			continueAt()
+		}
+	}
+
+	return removeUnusedLabels(structure(removeUnusedLabels(l.statements)))
+}
+
+func countReferences(statements []statement, references map[string]int) {
+	for _, s := range statements {
+		if s.kind == statementGoto || s.kind == statementConditionalGoto {
+			references[s.label]++
+		}
+		countReferences(s.body, references)
+		countReferences(s.orElse, references)
+	}
+}
+
+func removeUnusedLabels(statements []statement) []statement {
+	references := map[string]int{}
+	countReferences(statements, references)
+
+	var remove func([]statement) []statement
+	remove = func(statements []statement) []statement {
+		var kept []statement
+		for _, s := range statements {
+			if s.kind == statementLabel && references[s.label] == 0 {
+				continue
+			}
+			s.body = remove(s.body)
+			s.orElse = remove(s.orElse)
+			kept = append(kept, s)
+		}
+		return kept
+	}
+	return remove(statements)
+}
+
+func hasLabel(statements []statement) bool {
+	for _, s := range statements {
+		if s.kind == statementLabel {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOf(statements []statement, from int, kind statementKind, label string) int {
+	for i := from; i < len(statements); i++ {
+		if statements[i].kind == kind && statements[i].label == label {
+			return i
+		}
+	}
+	return -1
+}
+
+func splice(statements []statement, from, to int, replacement ...statement) []statement {
+	result := append([]statement{}, statements[:from]...)
+	result = append(result, replacement...)
+	return append(result, statements[to:]...)
+}
+
+// structure repeatedly replaces goto patterns whose bodies contain no
+// labels with if, if/else and loop statements. Labels are kept until the
+// end, and only removed once nothing jumps to them anymore.
+func structure(statements []statement) []statement {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(statements) && !changed; i++ {
+			if result, ok := reduce(statements, i); ok {
+				statements = result
+				changed = true
+			}
+		}
+	}
+	return statements
+}
+
+func reduce(statements []statement, i int) ([]statement, bool) {
+	s := statements[i]
+
+	switch s.kind {
+	case statementConditionalGoto:
+		// if c goto X; then; goto E; X: else; E:
+		// if c goto E; then; E:
+		target := indexOf(statements, i+1, statementLabel, s.label)
+		if target < 0 || hasLabel(statements[i+1:target]) {
+			return nil, false
+		}
+
+		// The else body is only entered through the conditional goto: any
+		// other goto X would land after the whole if/else instead.
+		references := map[string]int{}
+		countReferences(statements, references)
+
+		then := statements[i+1 : target]
+		if len(then) > 0 && then[len(then)-1].kind == statementGoto && references[s.label] == 1 {
+			end := indexOf(statements, target+1, statementLabel, then[len(then)-1].label)
+			if end >= 0 && !hasLabel(statements[target+1:end]) {
+				ifElse := statement{kind: statementIf, cond: s.cond, body: copyStatements(then[:len(then)-1]), orElse: copyStatements(statements[target+1 : end])}
+				return splice(statements, i, end, ifElse, statements[target]), true
+			}
+		}
+
+		if len(then) == 0 {
+			return nil, false
+		}
+		return splice(statements, i, target, statement{kind: statementIf, cond: s.cond, body: copyStatements(then)}), true
+
+	case statementLabel:
+		back := -1
+		for j := i + 1; j < len(statements) && statements[j].kind != statementLabel; j++ {
+			if (statements[j].kind == statementGoto || statements[j].kind == statementConditionalGoto) && statements[j].label == s.label {
+				back = j
+				break
+			}
+		}
+		if back < 0 {
+			return nil, false
+		}
+
+		body := copyStatements(statements[i+1 : back])
+		if statements[back].kind == statementConditionalGoto {
+			// L: body; if c goto L
+			loop := statement{kind: statementDoWhile, cond: statements[back].cond, body: body}
+			return splice(statements, i+1, back+1, loop), true
+		}
+
+		// L: pre; if c goto E; body; goto L; E:
+		for j, inner := range body {
+			if inner.kind == statementConditionalGoto && back+1 < len(statements) && statements[back+1].kind == statementLabel && statements[back+1].label == inner.label {
+				loop := statement{kind: statementWhile, cond: inner.cond, body: body[j+1:]}
+				if j > 0 {
+					exit := statement{kind: statementSimple, text: fmt.Sprintf("if %s { break }", inner.cond)}
+					loop = statement{kind: statementLoop, body: append(append(body[:j:j], exit), body[j+1:]...)}
+				}
+				return splice(statements, i+1, back+1, loop), true
+			}
+		}
+
+		// L: body; goto L
+		return splice(statements, i+1, back+1, statement{kind: statementLoop, body: body}), true
+	}
+
+	return nil, false
+}
+
+func copyStatements(statements []statement) []statement {
+	return append([]statement{}, statements...)
+}
+
+func writeStatements(b *strings.Builder, statements []statement, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, s := range statements {
+		switch s.kind {
+		case statementSimple:
+			fmt.Fprintf(b, "%s%s\n", indent, s.text)
+		case statementLabel:
+			fmt.Fprintf(b, "%s%s:\n", strings.Repeat("    ", depth-1), s.label)
+		case statementGoto:
+			fmt.Fprintf(b, "%sgoto %s\n", indent, s.label)
+		case statementConditionalGoto:
+			fmt.Fprintf(b, "%sif %s goto %s\n", indent, s.cond, s.label)
+		case statementIf:
+			fmt.Fprintf(b, "%sif %s {\n", indent, s.cond.negated())
+			writeStatements(b, s.body, depth+1)
+			if len(s.orElse) > 0 {
+				fmt.Fprintf(b, "%s} else {\n", indent)
+				writeStatements(b, s.orElse, depth+1)
+			}
+			fmt.Fprintf(b, "%s}\n", indent)
+		case statementWhile:
+			fmt.Fprintf(b, "%swhile %s {\n", indent, s.cond.negated())
+			writeStatements(b, s.body, depth+1)
+			fmt.Fprintf(b, "%s}\n", indent)
+		case statementDoWhile:
+			fmt.Fprintf(b, "%sdo {\n", indent)
+			writeStatements(b, s.body, depth+1)
+			fmt.Fprintf(b, "%s} while %s\n", indent, s.cond)
+		case statementLoop:
+			fmt.Fprintf(b, "%sloop {\n", indent)
+			writeStatements(b, s.body, depth+1)
+			fmt.Fprintf(b, "%s}\n", indent)
+		}
+	}
+}
+
+// WritePseudocode writes the decompiled program.
+func (decompiler *Decompiler) WritePseudocode(w io.Writer) error {
+	var b strings.Builder
+	for n, subroutine := range decompiler.cfg.Subroutines {
+		if n > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "func %s() {\n", functionName(subroutine))
+		writeStatements(&b, decompiler.function(subroutine), 1)
+		b.WriteString("}\n")
+	}
+
+	unreachable := 0
+	for _, block := range decompiler.cfg.Blocks {
+		if !block.Reachable {
+			unreachable += block.End - block.Start
+		}
+	}
+	if unreachable > 0 {
+		fmt.Fprintf(&b, "\n// %d unreachable instructions omitted\n", unreachable)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,141 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func decompile(t *testing.T, source string) string {
+	instructions, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("can not assemble: %s", err.Error())
+	}
+
+	var out bytes.Buffer
+	if err := NewDecompiler(instructions).WritePseudocode(&out); err != nil {
+		t.Fatalf("can not decompile: %s", err.Error())
+	}
+	return out.String()
+}
+
+func TestDecompileExpressions(t *testing.T) {
+	assert.Equal(t, "func main() {\n"+
+		"    mem[1] = getn()\n"+
+		"    t0 = mem[1]\n"+
+		"    putn((t0 * t0) - 3)\n"+
+		"    mem[2] = 10 / 4\n"+
+		"    exit\n"+
+		"}\n", decompile(t, `
+		push 1 getn
+		push 3 push 1 retrieve dup mul sub putn
+		push 2 push 4 push 10 div store
+		end`))
+}
+
+func TestDecompileStackAcrossBlocks(t *testing.T) {
+	assert.Equal(t, "func main() {\n"+
+		"    push(3)\n"+
+		"    sub_T()\n"+
+		"    exit\n"+
+		"}\n"+
+		"\n"+
+		"func sub_T() {\n"+
+		"    t0 = pop()\n"+
+		"    push(t0)\n"+
+		"    if t0 != 0 {\n"+
+		"        t1 = pop()\n"+
+		"        push(t1 - 1)\n"+
+		"        sub_T()\n"+
+		"    }\n"+
+		"    pop()\n"+
+		"    return\n"+
+		"}\n"+
+		"\n"+
+		"// 1 unreachable instructions omitted\n", decompile(t, `
+		push 3 call T end
+		label T dup jz S push 1 swap sub call T
+		label S discard ret
+		push 9`))
+}
+
+func TestDecompileIfElse(t *testing.T) {
+	assert.Equal(t, "func main() {\n"+
+		"    mem[0] = getn()\n"+
+		"    t0 = mem[0]\n"+
+		"    if t0 >= 0 {\n"+
+		"        putc(43)\n"+
+		"    } else {\n"+
+		"        putc(45)\n"+
+		"    }\n"+
+		"    exit\n"+
+		"}\n", decompile(t, `
+		push 0 getn push 0 retrieve jn S
+		push 43 putc jump T
+		label S push 45 putc
+		label T end`))
+}
+
+func TestDecompileElseWithOtherEntries(t *testing.T) {
+	assert.Equal(t, "func main() {\n"+
+		"    mem[0] = getn()\n"+
+		"    t0 = mem[0]\n"+
+		"    if t0 != 0 {\n"+
+		"        putn(1)\n"+
+		"        goto L_S\n"+
+		"    }\n"+
+		"L_T:\n"+
+		"    putn(2)\n"+
+		"L_S:\n"+
+		"    t1 = mem[0]\n"+
+		"    if t1 == 0 goto L_T\n"+
+		"    exit\n"+
+		"}\n", decompile(t, `
+		push 0 getn push 0 retrieve jz T
+		push 1 putn jump S
+		label T push 2 putn
+		label S push 0 retrieve jz T
+		end`))
+}
+
+func TestDecompileLoops(t *testing.T) {
+	assert.Equal(t, "func main() {\n"+
+		"    mem[0] = 10\n"+
+		"    loop {\n"+
+		"        t0 = mem[0]\n"+
+		"        if t0 == 0 { break }\n"+
+		"        t1 = mem[0]\n"+
+		"        mem[0] = t1 - 1\n"+
+		"    }\n"+
+		"    while 1 != 0 {\n"+
+		"        putn(1)\n"+
+		"    }\n"+
+		"    do {\n"+
+		"        mem[0] = getc()\n"+
+		"        t2 = mem[0]\n"+
+		"    } while t2 - 10 == 0\n"+
+		"    exit\n"+
+		"}\n", decompile(t, `
+		push 0 push 10 store
+		label S push 0 retrieve jz T
+		push 0 push 1 push 0 retrieve sub store jump S
+		label T
+		label SS push 1 jz ST push 1 putn jump SS
+		label ST
+		label TS push 0 getc push 10 push 0 retrieve sub jz TS
+		end`))
+}
+
+func TestDecompileUnstructuredJumps(t *testing.T) {
+	assert.Equal(t, "func main() {\n"+
+		"    goto L_T\n"+
+		"L_S:\n"+
+		"    putn(1)\n"+
+		"L_T:\n"+
+		"    putn(2)\n"+
+		"    goto L_S\n"+
+		"}\n", decompile(t, `
+		jump T
+		label S push 1 putn
+		label T push 2 putn jump S`))
+}
@@ -0,0 +1,203 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"strings"
+)
+
+type parameterKind int
+
+const (
+	noParameter parameterKind = iota
+	numberParameter
+	labelParameter
+)
+
+// command is a leaf of the command tree: the tokens that select an
+// instruction and the parameter that follows them.
+type command struct {
+	code      string
+	name      string
+	parameter parameterKind
+}
+
+var commands = []command{
+	{SPACE + SPACE, "push", numberParameter},
+	{SPACE + LF + SPACE, "dup", noParameter},
+	{SPACE + LF + TAB, "swap", noParameter},
+	{SPACE + LF + LF, "discard", noParameter},
+	{TAB + SPACE + SPACE + SPACE, "add", noParameter},
+	{TAB + SPACE + SPACE + TAB, "sub", noParameter},
+	{TAB + SPACE + SPACE + LF, "mul", noParameter},
+	{TAB + SPACE + TAB + SPACE, "div", noParameter},
+	{TAB + SPACE + TAB + TAB, "mod", noParameter},
+	{TAB + TAB + SPACE, "store", noParameter},
+	{TAB + TAB + TAB, "retrieve", noParameter},
+	{TAB + LF + SPACE + SPACE, "putc", noParameter},
+	{TAB + LF + SPACE + TAB, "putn", noParameter},
+	{TAB + LF + TAB + SPACE, "getc", noParameter},
+	{TAB + LF + TAB + TAB, "getn", noParameter},
+	{LF + SPACE + SPACE, "label", labelParameter},
+	{LF + SPACE + TAB, "call", labelParameter},
+	{LF + SPACE + LF, "jump", labelParameter},
+	{LF + TAB + SPACE, "jz", labelParameter},
+	{LF + TAB + TAB, "jn", labelParameter},
+	{LF + TAB + LF, "ret", noParameter},
+	{LF + LF + LF, "end", noParameter},
+}
+
+// suspiciousCharacters are the comment characters that strict parsing
+// reports besides other Unicode spaces, because editors and copy and paste
+// put them where a token was meant.
+var suspiciousCharacters = map[rune]string{
+	'\v':     "vertical tab",
+	'\f':     "form feed",
+	'\r':     "carriage return",
+	'\u0085': "next line",
+	'\u00A0': "no-break space",
+	'\u200B': "zero width space",
+	'\u2028': "line separator",
+	'\u2029': "paragraph separator",
+	'\u202F': "narrow no-break space",
+	'\u3000': "ideographic space",
+	'\uFEFF': "zero width no-break space",
+}
+
+// maxDiagnostics is the number of parse errors after which ParseAll stops.
+const maxDiagnostics = 20
+
+func tokenName(token string) string {
+	switch token {
+	case SPACE:
+		return "Space"
+	case TAB:
+		return "Tab"
+	case LF:
+		return "LF"
+	default:
+		return "end of file"
+	}
+}
+
+// expectedTokens explains which tokens may follow prefix, the tokens of an
+// instruction read so far.
+func expectedTokens(prefix string) string {
+	var alternatives []string
+	names := map[string][]string{}
+	for _, command := range commands {
+		if len(command.code) > len(prefix) && strings.HasPrefix(command.code, prefix) {
+			next := command.code[len(prefix) : len(prefix)+1]
+			if names[next] == nil {
+				alternatives = append(alternatives, next)
+			}
+			names[next] = append(names[next], command.name)
+		}
+	}
+	if len(alternatives) > 0 {
+		var choices []string
+		for _, next := range alternatives {
+			choices = append(choices, fmt.Sprintf("%s (%s)", tokenName(next), strings.Join(names[next], ", ")))
+		}
+		return joinChoices(choices)
+	}
+
+	for _, command := range commands {
+		if !strings.HasPrefix(prefix, command.code) {
+			continue
+		}
+		parameter := prefix[len(command.code):]
+		switch command.parameter {
+		case numberParameter:
+			if parameter == "" {
+				return joinChoices([]string{"Space (positive)", "Tab (negative)"})
+			}
+			if len(parameter) == 1 {
+				return joinChoices([]string{"Space (0)", "Tab (1)"})
+			}
+			return joinChoices([]string{"Space (0)", "Tab (1)", "LF (end of number)"})
+		case labelParameter:
+			if parameter == "" {
+				return joinChoices([]string{"Space", "Tab"})
+			}
+			return joinChoices([]string{"Space", "Tab", "LF (end of label)"})
+		}
+	}
+	return ""
+}
+
+func joinChoices(choices []string) string {
+	if len(choices) == 1 {
+		return choices[0]
+	}
+	return strings.Join(choices[:len(choices)-1], ", ") + " or " + choices[len(choices)-1]
+}
+
+// Diagnostic is a parse error. Position is the offending token, or the end
+// of the source when the source ends in the middle of an instruction.
+type Diagnostic struct {
+	Filename string
+	Position Position
+	Message  string
+	Found    string
+	Expected string
+	// Source is the line containing Position, with its LF if it has one.
+	Source string
+}
+
+func (diagnostic *Diagnostic) Error() string {
+	return fmt.Sprintf("Parse error: %s at %s:%s", diagnostic.Message, diagnostic.Filename, diagnostic.Position)
+}
+
+// Render returns the error followed by the offending line, with spaces,
+// tabs, linefeeds and carriage returns drawn as ·, →, ¶ and ␍, a caret
+// under the column and what was expected there.
+func (diagnostic *Diagnostic) Render() string {
+	var builder strings.Builder
+	line := fmt.Sprint(diagnostic.Position.Line)
+	gutter := strings.Repeat(" ", len(line))
+
+	fmt.Fprintln(&builder, diagnostic.Error())
+	fmt.Fprintf(&builder, " %s | %s\n", line, visibleWhitespace(diagnostic.Source))
+	fmt.Fprintf(&builder, " %s | %s^\n", gutter, strings.Repeat(" ", diagnostic.Position.Column-1))
+	if diagnostic.Expected != "" {
+		fmt.Fprintf(&builder, " %s = found %s, expected %s\n", gutter, diagnostic.Found, diagnostic.Expected)
+	}
+	return builder.String()
+}
+
+func visibleWhitespace(source string) string {
+	return strings.NewReplacer(SPACE, "·", TAB, "→", LF, "¶", "\r", "␍").Replace(source)
+}
+
+// ParseErrors is every parse error of a source, in source order.
+type ParseErrors []*Diagnostic
+
+func (errs ParseErrors) Error() string {
+	messages := make([]string, len(errs))
+	for n, diagnostic := range errs {
+		messages[n] = diagnostic.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Render renders every diagnostic.
+func (errs ParseErrors) Render() string {
+	var builder strings.Builder
+	for _, diagnostic := range errs {
+		builder.WriteString(diagnostic.Render())
+	}
+	return builder.String()
+}
+
+// RenderError renders parse errors with their source excerpts and any other
+// error as its message.
+func RenderError(err error) string {
+	switch err := err.(type) {
+	case ParseErrors:
+		return strings.TrimSuffix(err.Render(), "\n")
+	case *Diagnostic:
+		return strings.TrimSuffix(err.Render(), "\n")
+	default:
+		return err.Error()
+	}
+}
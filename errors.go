@@ -1,16 +1,105 @@
 package whitespace_go
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
 
 func parseError(parser *Parser, message string) error {
-	errorMessage := fmt.Sprintf("Parse error: %s at %s:%d:%d", message, parser.filename, parser.currentLine, parser.currentColumn)
-	return errors.New(errorMessage)
+	position, source := parser.currentPosition()
+	found, prefix := "", string(parser.command)
+	if parser.currentIndex < len(parser.sourceCode) {
+		found = string(parser.currentToken())
+		prefix = prefix[:len(prefix)-1]
+	}
+
+	return &Diagnostic{
+		Filename: parser.filename,
+		Position: position,
+		Message:  message,
+		Found:    tokenName(found),
+		Expected: expectedTokens(prefix),
+		Source:   source,
+	}
+}
+
+// characterError reports r, the comment character under the cursor, as a
+// suspicious character.
+func characterError(parser *Parser, r rune, name string) *Diagnostic {
+	position, source := parser.positionAt(parser.currentIndex)
+	return &Diagnostic{
+		Filename: parser.filename,
+		Position: position,
+		Message:  fmt.Sprintf("suspicious character %U (%s)", r, name),
+		Found:    fmt.Sprintf("%U", r),
+		Source:   source,
+	}
+}
+
+// RuntimeError is the error an instruction fails with. PC is the
+// instruction that failed, or -1 when no instruction did.
+type RuntimeError struct {
+	PC      int
+	Message string
+}
+
+func (err *RuntimeError) Error() string {
+	return fmt.Sprintf("Runtime error: %s", err.Message)
+}
+
+// CanceledError is the error a run stops with when its context is done. PC
+// is the instruction that would have run next and Err the error of the
+// context, context.Canceled or context.DeadlineExceeded.
+type CanceledError struct {
+	PC  int
+	Err error
+}
+
+func (err *CanceledError) Error() string {
+	if err.Err == context.DeadlineExceeded {
+		return "Canceled: timed out"
+	}
+	return fmt.Sprintf("Canceled: %s", err.Err.Error())
+}
+
+func (err *CanceledError) Unwrap() error {
+	return err.Err
+}
+
+// LimitError is the error a run stops with when it would go over one of its
+// Limits. PC is the instruction that would have, and Max the limit.
+type LimitError struct {
+	PC    int
+	Limit Limit
+	Max   int
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("Limit exceeded: %s above %d", err.Limit, err.Max)
+}
+
+// WatchpointError is the error a run stops with when a watchpoint with Break
+// set triggers. PC is the instruction that triggered it.
+type WatchpointError struct {
+	PC         int
+	Watchpoint Watchpoint
+	Message    string
+}
+
+func (err *WatchpointError) Error() string {
+	return fmt.Sprintf("Watchpoint %s: %s", err.Watchpoint, err.Message)
 }
 
 func runtimeError(executor *Executor, message string) error {
-	errorMessage := fmt.Sprintf("Runtime error: %s", message)
+	return &RuntimeError{PC: executor.programCounter, Message: message}
+}
+
+func watchpointError(pc int, watchpoint Watchpoint, message string) error {
+	return &WatchpointError{PC: pc, Watchpoint: watchpoint, Message: message}
+}
+
+func assembleError(message string) error {
+	errorMessage := fmt.Sprintf("Assemble error: %s", message)
 	return errors.New(errorMessage)
 }
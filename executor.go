@@ -1,26 +1,184 @@
 package whitespace_go
 
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+// Executor runs a program. It owns the state of a run, the stack, heap,
+// call stack and I/O, and only reads the instructions and label table, so
+// executors made by Program.NewExecutor can run one program concurrently.
 type Executor struct {
 	instructions   []Instruction
+	labels         map[string]int
 	stack          []int
 	heap           map[int]int
 	programCounter int
 	callStack      []int
+	hooks          []Hook
+	stdin          *bufio.Reader
+	stdout         io.Writer
+	inputOffset    int64
+	limits         Limits
+	steps          int
+	outputBytes    int
+}
+
+// Hook observes every instruction the executor runs. Before is called with
+// the program counter of the instruction about to be executed and After is
+// called with the same program counter once it has been executed.
+type Hook interface {
+	Before(executor *Executor, pc int) error
+	After(executor *Executor, pc int) error
 }
 
+func (executor *Executor) AddHook(hook Hook) {
+	executor.hooks = append(executor.hooks, hook)
+}
+
+// cancelCheckInterval is how many instructions run between two checks of
+// the context. It is a power of two so the check is a mask.
+const cancelCheckInterval = 1024
+
 func (executor *Executor) Run() error {
+	return executor.RunContext(context.Background())
+}
+
+// RunContext runs the program until it ends, fails or ctx is done, in which
+// case it returns a *CanceledError. The context is checked before the first
+// instruction and then every cancelCheckInterval instructions; a getc or
+// getn waiting for input is not interrupted.
+func (executor *Executor) RunContext(ctx context.Context) error {
 	executor.heap = map[int]int{}
-	executor.programCounter = 0
+	executor.steps = 0
+	executor.outputBytes = 0
+
+	return executor.runFrom(ctx, 0)
+}
+
+// runFrom continues execution at pc with the current stack, heap and call
+// stack until the program counter leaves the instruction list.
+func (executor *Executor) runFrom(ctx context.Context, pc int) error {
+	done := ctx.Done()
+	for executor.programCounter = pc; executor.programCounter < len(executor.instructions); executor.programCounter++ {
+		if executor.steps&(cancelCheckInterval-1) == 0 {
+			select {
+			case <-done:
+				return &CanceledError{PC: executor.programCounter, Err: ctx.Err()}
+			default:
+			}
+		}
+		if exceeds(executor.steps+1, executor.limits.MaxSteps) {
+			return limitError(executor, StepLimit, executor.limits.MaxSteps)
+		}
+
+		if err := executor.step(); err != nil {
+			return err
+		}
+		executor.steps++
+	}
+
+	return nil
+}
+
+func (executor *Executor) step() error {
+	pc := executor.programCounter
+
+	for _, hook := range executor.hooks {
+		if err := hook.Before(executor, pc); err != nil {
+			return err
+		}
+	}
+
+	if err := executor.instructions[pc].Execute(executor); err != nil {
+		return err
+	}
 
-	for executor.programCounter = 0; executor.programCounter < len(executor.instructions); executor.programCounter++ {
-		executor.instructions[executor.programCounter].Execute(executor)
+	for _, hook := range executor.hooks {
+		if err := hook.After(executor, pc); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (executor *Executor) Push(value int) {
+// SetInput replaces the reader Getc and Getn read from, which is standard
+// input by default.
+func (executor *Executor) SetInput(r io.Reader) {
+	executor.stdin = bufio.NewReader(r)
+}
+
+// SetOutput replaces the writer Putc and Putn write to, which is standard
+// output by default.
+func (executor *Executor) SetOutput(w io.Writer) {
+	executor.stdout = w
+}
+
+func (executor *Executor) output() io.Writer {
+	if executor.stdout == nil {
+		return os.Stdout
+	}
+	return executor.stdout
+}
+
+// write writes text to the output unless it would take the output over its
+// limit.
+func (executor *Executor) write(text string) error {
+	if exceeds(executor.outputBytes+len(text), executor.limits.MaxOutputBytes) {
+		return limitError(executor, OutputBytesLimit, executor.limits.MaxOutputBytes)
+	}
+	executor.outputBytes += len(text)
+	io.WriteString(executor.output(), text)
+	return nil
+}
+
+func (executor *Executor) readLine() (string, error) {
+	if executor.stdin == nil {
+		executor.SetInput(os.Stdin)
+	}
+
+	line, err := executor.stdin.ReadString('\n')
+	executor.inputOffset += int64(len(line))
+	if err == io.EOF && len(line) == 0 {
+		return "", runtimeError(executor, "input is empty")
+	}
+	if err != nil && err != io.EOF {
+		return "", runtimeError(executor, "can not read input: "+err.Error())
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// jump moves to the MarkLabel of label, from the label table built the
+// first time it is needed unless the program was linked already.
+func (executor *Executor) jump(label string) error {
+	if executor.labels == nil {
+		executor.labels = labelTable(executor.instructions)
+	}
+
+	pc, ok := executor.labels[label]
+	if !ok {
+		return runtimeError(executor, "label not found")
+	}
+	executor.programCounter = pc
+	return nil
+}
+
+// Push pushes value unless it is over the magnitude limit or the stack is
+// full.
+func (executor *Executor) Push(value int) error {
+	if err := executor.checkMagnitude(value); err != nil {
+		return err
+	}
+	if exceeds(len(executor.stack)+1, executor.limits.MaxStackSize) {
+		return limitError(executor, StackSizeLimit, executor.limits.MaxStackSize)
+	}
 	executor.stack = append(executor.stack, value)
+	return nil
 }
 
 func (executor *Executor) Pop() (int, error) {
@@ -33,8 +191,27 @@ func (executor *Executor) Pop() (int, error) {
 	return value, nil
 }
 
-func (executor *Executor) PushCallStack(counter int) {
+// PushCallStack records counter to return to unless the calls are as deep
+// as their limit.
+func (executor *Executor) PushCallStack(counter int) error {
+	if exceeds(len(executor.callStack)+1, executor.limits.MaxCallDepth) {
+		return limitError(executor, CallDepthLimit, executor.limits.MaxCallDepth)
+	}
 	executor.callStack = append(executor.callStack, counter)
+	return nil
+}
+
+// store stores value at address unless it is over the magnitude limit or
+// the heap is full.
+func (executor *Executor) store(address int, value int) error {
+	if err := executor.checkMagnitude(value); err != nil {
+		return err
+	}
+	if _, ok := executor.heap[address]; !ok && exceeds(len(executor.heap)+1, executor.limits.MaxHeapCells) {
+		return limitError(executor, HeapCellsLimit, executor.limits.MaxHeapCells)
+	}
+	executor.heap[address] = value
+	return nil
 }
 
 func (executor *Executor) PopCallStack() (int, error) {
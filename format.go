@@ -0,0 +1,129 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// lineBreak ends the line of an instruction without a trailing LF. A lone CR
+// is a comment, but editors still start a new line after it.
+const lineBreak = "\r"
+
+// Format rewrites source so that every instruction is introduced by its
+// mnemonic, with the character it pushes when it pushes a printable one:
+//
+//	push 72 ; 'H'
+//
+// A space in a comment would be a token, so the words of the annotation are
+// separated by the instruction's own tokens. Flow control instructions put
+// their leading LF first, so their annotation starts a line, and an
+// instruction that does not end with an LF is followed by lineBreak, so
+// every instruction is on a line of its own. The words of the comments
+// around an instruction follow its annotation, and annotations written by
+// an earlier Format are recognized and replaced, so formatting twice changes
+// nothing. The result parses to the same instructions as source.
+func Format(filename string, source string, options ParseOptions) (string, error) {
+	if options.notation() != WhitespaceNotation {
+		return "", fmt.Errorf("%s: only Whitespace sources can be formatted", filename)
+	}
+
+	parser := NewParser(filename, source)
+	parser.Options = options
+	if err := parser.ParseAll(); err != nil {
+		return "", err
+	}
+	if len(parser.Instructions) == 0 {
+		return source, nil
+	}
+
+	var builder strings.Builder
+	start := 0
+	for pc, instruction := range parser.Instructions {
+		position := parser.Positions[pc]
+		end := position.End
+		if pc == len(parser.Instructions)-1 {
+			end = len(source)
+		}
+
+		annotation := annotate(instruction)
+		comments := strings.Fields(source[start:end])
+		if hasPrefix(comments, annotation) {
+			comments = comments[len(annotation):]
+		}
+		words := append(annotation, comments...)
+
+		tokens := instructionTokens(source[position.Offset:position.End])
+		if pc > 0 && !strings.HasSuffix(builder.String(), LF) && string(tokens[0]) != LF {
+			builder.WriteString(lineBreak)
+		}
+		writeAnnotated(&builder, words, tokens)
+		start = end
+	}
+
+	formatted := builder.String()
+	check := NewParser(filename, formatted)
+	check.Options = options
+	// The line breaks are comments on purpose.
+	check.Options.Strict = false
+	if err := check.ParseAll(); err != nil || !reflect.DeepEqual(check.Instructions, parser.Instructions) {
+		return "", fmt.Errorf("%s: formatting changed the program", filename)
+	}
+	return formatted, nil
+}
+
+// annotate returns the words of the mnemonic of instruction, followed by the
+// character it pushes.
+func annotate(instruction Instruction) []string {
+	words := strings.Fields(Mnemonic(instruction))
+	if push, ok := instruction.(Push); ok && push.value > ' ' && push.value <= unicode.MaxRune {
+		r := rune(push.value)
+		if unicode.IsGraphic(r) && !unicode.IsSpace(r) {
+			words = append(words, ";", fmt.Sprintf("%q", r))
+		}
+	}
+	return words
+}
+
+func hasPrefix(words []string, prefix []string) bool {
+	if len(words) < len(prefix) {
+		return false
+	}
+	for n := range prefix {
+		if words[n] != prefix[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func instructionTokens(source string) []rune {
+	var tokens []rune
+	for _, r := range source {
+		if contains(r) {
+			tokens = append(tokens, r)
+		}
+	}
+	return tokens
+}
+
+// writeAnnotated writes the tokens of an instruction with words between
+// them. Every word is followed by a token, so words that do not fit are
+// joined to the last one that does.
+func writeAnnotated(builder *strings.Builder, words []string, tokens []rune) {
+	if string(tokens[0]) == LF && len(tokens) > 1 {
+		builder.WriteRune(tokens[0])
+		tokens = tokens[1:]
+	}
+
+	if len(words) > len(tokens) {
+		words = append(words[:len(tokens)-1], strings.Join(words[len(tokens)-1:], ""))
+	}
+
+	for n, word := range words {
+		builder.WriteString(word)
+		builder.WriteRune(tokens[n])
+	}
+	builder.WriteString(string(tokens[len(words):]))
+}
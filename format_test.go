@@ -0,0 +1,53 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	source := "greet" + SPACE + SPACE + SPACE + TAB + SPACE + SPACE + TAB + SPACE + SPACE + SPACE + LF +
+		TAB + LF + SPACE + SPACE +
+		LF + SPACE + SPACE + "loop" + TAB + LF +
+		LF + LF + LF
+	formatted, err := Format("format.ws", source, ParseOptions{})
+	assert.Nil(t, err)
+
+	expected := "push" + SPACE + "72" + SPACE + ";" + SPACE + "'H'" + TAB + "greet" + SPACE + SPACE + TAB + SPACE + SPACE + SPACE + LF +
+		"putc" + TAB + LF + SPACE + SPACE +
+		LF + "label" + SPACE + "T" + SPACE + "loop" + TAB + LF +
+		LF + "end" + LF + LF
+	assert.Equal(t, expected, formatted)
+
+	again, err := Format("format.ws", formatted, ParseOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, formatted, again)
+}
+
+func TestFormatInstructionsWithoutLF(t *testing.T) {
+	source := "x" + TAB + SPACE + SPACE + SPACE + TAB + "y" + SPACE + SPACE + TAB + TAB + TAB + TAB + LF + LF + LF
+	formatted, err := Format("format.ws", source, ParseOptions{Strict: true})
+	assert.Nil(t, err)
+
+	expected := "add" + TAB + "x" + SPACE + SPACE + SPACE + "\r" +
+		"sub" + TAB + "y" + SPACE + SPACE + TAB + "\r" +
+		"retrieve" + TAB + TAB + TAB + LF +
+		"end" + LF + LF
+	assert.Equal(t, expected, formatted)
+
+	again, err := Format("format.ws", formatted, ParseOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, formatted, again)
+}
+
+func TestFormatJoinsWordsThatDoNotFit(t *testing.T) {
+	source := "a" + TAB + "b" + TAB + "c" + SPACE + "d"
+	formatted, err := Format("format.ws", source, ParseOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "store"+TAB+"a"+TAB+"bcd"+SPACE, formatted)
+}
+
+func TestFormatReportsParseErrors(t *testing.T) {
+	_, err := Format("format.ws", SPACE+TAB, ParseOptions{})
+	assert.Equal(t, "Parse error: expected stack manipulation command at format.ws:1:2", err.Error())
+}
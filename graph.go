@@ -0,0 +1,174 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func subroutineName(label string) string {
+	if label == "" {
+		return mainFunction
+	}
+	return LabelName(label)
+}
+
+func (cfg *ControlFlowGraph) blockText(block *BasicBlock) []string {
+	lines := make([]string, 0, block.End-block.Start)
+	for pc := block.Start; pc < block.End; pc++ {
+		lines = append(lines, fmt.Sprintf("%d: %s", pc, Mnemonic(cfg.Instructions[pc])))
+	}
+	return lines
+}
+
+// clusters groups the blocks by the subroutine they belong to, in the order
+// of Subroutines, followed by the unreachable blocks.
+func (cfg *ControlFlowGraph) clusters() ([][]*BasicBlock, []*BasicBlock) {
+	index := map[string]int{}
+	for n, name := range cfg.Subroutines {
+		index[name] = n
+	}
+
+	clusters := make([][]*BasicBlock, len(cfg.Subroutines))
+	var unreachable []*BasicBlock
+	for _, block := range cfg.Blocks {
+		if block.Reachable {
+			clusters[index[block.Subroutine]] = append(clusters[index[block.Subroutine]], block)
+		} else {
+			unreachable = append(unreachable, block)
+		}
+	}
+	return clusters, unreachable
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func dotQuote(s string) string {
+	return `"` + dotEscape(s) + `"`
+}
+
+// WriteDOT writes the control-flow graph in the Graphviz DOT language with
+// one cluster per subroutine.
+func (cfg *ControlFlowGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph cfg {\n")
+	b.WriteString("  node [shape=box fontname=\"monospace\"];\n")
+
+	writeBlock := func(indent string, block *BasicBlock) {
+		var label strings.Builder
+		for _, line := range cfg.blockText(block) {
+			label.WriteString(dotEscape(line) + `\l`)
+		}
+		fmt.Fprintf(&b, "%sb%d [label=\"%s\"];\n", indent, block.ID, label.String())
+	}
+
+	clusters, unreachable := cfg.clusters()
+	for n, blocks := range clusters {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", n)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(subroutineName(cfg.Subroutines[n])))
+		for _, block := range blocks {
+			writeBlock("    ", block)
+		}
+		b.WriteString("  }\n")
+	}
+	for _, block := range unreachable {
+		writeBlock("  ", block)
+	}
+
+	for _, block := range cfg.Blocks {
+		for _, edge := range block.Edges {
+			style := ""
+			if edge.Kind == EdgeReturn {
+				style = " style=dashed"
+			}
+			fmt.Fprintf(&b, "  b%d -> b%d [label=%s%s];\n", block.ID, edge.To, dotQuote(edge.Kind.String()), style)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// WriteMermaid writes the control-flow graph as a Mermaid flowchart with
+// one subgraph per subroutine.
+func (cfg *ControlFlowGraph) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	writeBlock := func(indent string, block *BasicBlock) {
+		fmt.Fprintf(&b, "%sb%d[%s]\n", indent, block.ID, mermaidQuote(strings.Join(cfg.blockText(block), "<br/>")))
+	}
+
+	clusters, unreachable := cfg.clusters()
+	for n, blocks := range clusters {
+		fmt.Fprintf(&b, "  subgraph s%d[%s]\n", n, mermaidQuote(subroutineName(cfg.Subroutines[n])))
+		for _, block := range blocks {
+			writeBlock("    ", block)
+		}
+		b.WriteString("  end\n")
+	}
+	for _, block := range unreachable {
+		writeBlock("  ", block)
+	}
+
+	for _, block := range cfg.Blocks {
+		for _, edge := range block.Edges {
+			arrow := "-->"
+			if edge.Kind == EdgeReturn {
+				arrow = "-.->"
+			}
+			fmt.Fprintf(&b, "  b%d %s|%s| b%d\n", block.ID, arrow, edge.Kind, edge.To)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCallGraphDOT writes which subroutines call which in the Graphviz DOT
+// language.
+func (cfg *ControlFlowGraph) WriteCallGraphDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph calls {\n")
+	for _, caller := range cfg.Subroutines {
+		fmt.Fprintf(&b, "  %s;\n", dotQuote(subroutineName(caller)))
+	}
+	for _, caller := range cfg.Subroutines {
+		for _, callee := range cfg.Calls[caller] {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(subroutineName(caller)), dotQuote(subroutineName(callee)))
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCallGraphMermaid writes which subroutines call which as a Mermaid
+// flowchart.
+func (cfg *ControlFlowGraph) WriteCallGraphMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for n, caller := range cfg.Subroutines {
+		fmt.Fprintf(&b, "  s%d[%s]\n", n, mermaidQuote(subroutineName(caller)))
+	}
+	index := map[string]int{}
+	for n, name := range cfg.Subroutines {
+		index[name] = n
+	}
+	for _, caller := range cfg.Subroutines {
+		for _, callee := range cfg.Calls[caller] {
+			fmt.Fprintf(&b, "  s%d --> s%d\n", index[caller], index[callee])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
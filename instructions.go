@@ -1,12 +1,12 @@
 package whitespace_go
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strconv"
 )
 
+const minInt = -int(^uint(0)>>1) - 1
+
 type Instruction interface {
 	Execute(executor *Executor) error
 }
@@ -15,10 +15,13 @@ type Push struct {
 	value int
 }
 
-func (p Push) Execute(executor *Executor) error {
-	executor.stack = append(executor.stack, p.value)
+// Value returns the number p pushes.
+func (p Push) Value() int {
+	return p.value
+}
 
-	return nil
+func (p Push) Execute(executor *Executor) error {
+	return executor.Push(p.value)
 }
 
 type Swap struct{}
@@ -35,8 +38,7 @@ func (s Swap) Execute(executor *Executor) error {
 	}
 
 	executor.Push(a)
-	executor.Push(b)
-	return nil
+	return executor.Push(b)
 }
 
 type Duplicate struct{}
@@ -48,8 +50,7 @@ func (d Duplicate) Execute(executor *Executor) error {
 	}
 
 	executor.Push(a)
-	executor.Push(a)
-	return nil
+	return executor.Push(a)
 }
 
 type Discard struct{}
@@ -73,9 +74,12 @@ func (a Addition) Execute(executor *Executor) error {
 		return errRhs
 	}
 
-	executor.Push(lhs + rhs)
+	sum := lhs + rhs
+	if (lhs > 0 && rhs > 0 && sum < 0) || (lhs < 0 && rhs < 0 && sum >= 0) {
+		return executor.overflowError()
+	}
 
-	return nil
+	return executor.Push(sum)
 }
 
 type Subtraction struct{}
@@ -91,9 +95,12 @@ func (s Subtraction) Execute(executor *Executor) error {
 		return errRhs
 	}
 
-	executor.Push(lhs - rhs)
+	difference := lhs - rhs
+	if (rhs > 0 && difference > lhs) || (rhs < 0 && difference < lhs) {
+		return executor.overflowError()
+	}
 
-	return nil
+	return executor.Push(difference)
 }
 
 type Multiplication struct{}
@@ -109,9 +116,12 @@ func (m Multiplication) Execute(executor *Executor) error {
 		return errRhs
 	}
 
-	executor.Push(lhs * rhs)
+	product := lhs * rhs
+	if lhs != 0 && (product/lhs != rhs || (lhs == -1 && rhs == minInt)) {
+		return executor.overflowError()
+	}
 
-	return nil
+	return executor.Push(product)
 }
 
 type Division struct{}
@@ -127,9 +137,14 @@ func (d Division) Execute(executor *Executor) error {
 		return errRhs
 	}
 
-	executor.Push(lhs / rhs)
+	if rhs == 0 {
+		return runtimeError(executor, "division by zero")
+	}
+	if lhs == minInt && rhs == -1 {
+		return executor.overflowError()
+	}
 
-	return nil
+	return executor.Push(lhs / rhs)
 }
 
 type Modulo struct{}
@@ -145,18 +160,20 @@ func (m Modulo) Execute(executor *Executor) error {
 		return errRhs
 	}
 
-	executor.Push(lhs % rhs)
+	if rhs == 0 {
+		return runtimeError(executor, "division by zero")
+	}
 
-	return nil
+	return executor.Push(lhs % rhs)
 }
 
 type Getc struct{}
 
 func (g Getc) Execute(executor *Executor) error {
-
-	stdin := bufio.NewScanner(os.Stdin)
-	stdin.Scan()
-	text := stdin.Text()
+	text, err := executor.readLine()
+	if err != nil {
+		return err
+	}
 
 	if len(text) == 0 {
 		return runtimeError(executor, "input is empty")
@@ -167,16 +184,17 @@ func (g Getc) Execute(executor *Executor) error {
 		return err
 	}
 
-	executor.heap[address] = int([]rune(text)[0])
-	return nil
+	return executor.store(address, int([]rune(text)[0]))
 }
 
 type Getn struct{}
 
 func (g Getn) Execute(executor *Executor) error {
-	stdin := bufio.NewScanner(os.Stdin)
-	stdin.Scan()
-	text := stdin.Text()
+	text, err := executor.readLine()
+	if err != nil {
+		return err
+	}
+
 	n, err := strconv.Atoi(text)
 	if err != nil {
 		return runtimeError(executor, "input character is not numeric")
@@ -187,8 +205,7 @@ func (g Getn) Execute(executor *Executor) error {
 		return err
 	}
 
-	executor.heap[address] = n
-	return nil
+	return executor.store(address, n)
 }
 
 type Putc struct{}
@@ -199,9 +216,7 @@ func (p Putc) Execute(executor *Executor) error {
 		return err
 	}
 
-	fmt.Printf("%c", n)
-
-	return nil
+	return executor.write(fmt.Sprintf("%c", n))
 }
 
 type Putn struct{}
@@ -212,9 +227,7 @@ func (p Putn) Execute(executor *Executor) error {
 		return err
 	}
 
-	fmt.Printf("%d", n)
-
-	return nil
+	return executor.write(strconv.Itoa(n))
 }
 
 type Store struct{}
@@ -230,8 +243,7 @@ func (s Store) Execute(executor *Executor) error {
 		return errAddress
 	}
 
-	executor.heap[address] = value
-	return nil
+	return executor.store(address, value)
 }
 
 type Retrieve struct{}
@@ -247,14 +259,18 @@ func (r Retrieve) Execute(executor *Executor) error {
 		return runtimeError(executor, "invalid heap access")
 	}
 
-	executor.Push(value)
-	return nil
+	return executor.Push(value)
 }
 
 type MarkLabel struct {
 	label string
 }
 
+// Label returns the label m marks, made of spaces and tabs.
+func (m MarkLabel) Label() string {
+	return m.label
+}
+
 func (m MarkLabel) Execute(executor *Executor) error {
 	return nil
 }
@@ -263,19 +279,18 @@ type CallSubroutine struct {
 	label string
 }
 
+// Label returns the label c calls, made of spaces and tabs.
+func (c CallSubroutine) Label() string {
+	return c.label
+}
+
 func (c CallSubroutine) Execute(executor *Executor) error {
 	counter := executor.programCounter
-	executor.PushCallStack(counter)
-
-	for i, instruction := range executor.instructions {
-		m, ok := instruction.(MarkLabel)
-		if ok && m.label == c.label {
-			executor.programCounter = i
-			return nil
-		}
+	if err := executor.PushCallStack(counter); err != nil {
+		return err
 	}
 
-	return runtimeError(executor, "label not found")
+	return executor.jump(c.label)
 }
 
 type EndSubroutine struct{}
@@ -294,22 +309,24 @@ type JumpLabel struct {
 	label string
 }
 
-func (j JumpLabel) Execute(executor *Executor) error {
-	for i, instruction := range executor.instructions {
-		m, ok := instruction.(MarkLabel)
-		if ok && m.label == j.label {
-			executor.programCounter = i
-			return nil
-		}
-	}
+// Label returns the label j jumps to, made of spaces and tabs.
+func (j JumpLabel) Label() string {
+	return j.label
+}
 
-	return runtimeError(executor, "label not found")
+func (j JumpLabel) Execute(executor *Executor) error {
+	return executor.jump(j.label)
 }
 
 type JumpLabelWhenZero struct {
 	label string
 }
 
+// Label returns the label j jumps to, made of spaces and tabs.
+func (j JumpLabelWhenZero) Label() string {
+	return j.label
+}
+
 func (j JumpLabelWhenZero) Execute(executor *Executor) error {
 	value, err := executor.Pop()
 	if err != nil {
@@ -320,21 +337,18 @@ func (j JumpLabelWhenZero) Execute(executor *Executor) error {
 		return nil
 	}
 
-	for i, instruction := range executor.instructions {
-		m, ok := instruction.(MarkLabel)
-		if ok && m.label == j.label {
-			executor.programCounter = i
-			return nil
-		}
-	}
-
-	return runtimeError(executor, "label not found")
+	return executor.jump(j.label)
 }
 
 type JumpLabelWhenNegative struct {
 	label string
 }
 
+// Label returns the label j jumps to, made of spaces and tabs.
+func (j JumpLabelWhenNegative) Label() string {
+	return j.label
+}
+
 func (j JumpLabelWhenNegative) Execute(executor *Executor) error {
 	value, err := executor.Pop()
 	if err != nil {
@@ -345,15 +359,7 @@ func (j JumpLabelWhenNegative) Execute(executor *Executor) error {
 		return nil
 	}
 
-	for i, instruction := range executor.instructions {
-		m, ok := instruction.(MarkLabel)
-		if ok && m.label == j.label {
-			executor.programCounter = i
-			return nil
-		}
-	}
-
-	return runtimeError(executor, "label not found")
+	return executor.jump(j.label)
 }
 
 type EndProgram struct{}
@@ -207,3 +207,23 @@ func TestEndProgram(t *testing.T) {
 
 	assert.Equal(t, executor.programCounter, 1)
 }
+
+func TestRunStopsAtFailingInstruction(t *testing.T) {
+	executor := newExecutor()
+	executor.instructions = []Instruction{Push{value: 1}, Addition{}, Push{value: 2}}
+
+	err := executor.Run()
+
+	assert.EqualError(t, err, "Runtime error: stack is epmty")
+	assert.Equal(t, executor.programCounter, 1)
+	assert.Empty(t, executor.stack)
+}
+
+func TestRuntimeErrorRecordsInstruction(t *testing.T) {
+	executor := &Executor{instructions: []Instruction{Push{value: 1}, Addition{}}}
+
+	err := executor.Run()
+
+	assert.Equal(t, &RuntimeError{PC: 1, Message: "stack is epmty"}, err)
+	assert.EqualError(t, err, "Runtime error: stack is epmty")
+}
@@ -1,11 +1,18 @@
 package whitespace_go
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 var (
@@ -15,10 +22,12 @@ var (
 const version = "v0.0.1"
 
 type Interpreter struct {
-	args     []string
-	stderr   io.Writer
-	parser   Parser
-	executor Executor
+	args         []string
+	stderr       io.Writer
+	parseOptions ParseOptions
+	notationOpt  string
+	parser       Parser
+	executor     Executor
 }
 
 func New() *Interpreter {
@@ -30,7 +39,7 @@ func New() *Interpreter {
 
 func (i *Interpreter) Run() int {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s:\n  ws [FILE]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n  ws [FILE]\n  ws run [OPTIONS] FILE\n  ws cover [OPTIONS] FILE PROFILE...\n  ws resume [OPTIONS] SNAPSHOT FILE\n  ws check [OPTIONS] FILE\n  ws lint [OPTIONS] FILE\n  ws cfg [OPTIONS] FILE\n  ws decompile [OPTIONS] FILE\n  ws symex [OPTIONS] FILE\n  ws analyze [OPTIONS] FILE\n  ws fmt [OPTIONS] FILE\n  ws minify [OPTIONS] FILE\n  ws embed --carrier CARRIER [OPTIONS] FILE\n  ws extract [OPTIONS] FILE\n  ws convert [OPTIONS] FILE\n  ws build [OPTIONS] FILE\n  ws dump [OPTIONS] FILE\n  ws batch [OPTIONS] FILE INPUT...\n  ws repl\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
@@ -39,33 +48,1129 @@ func (i *Interpreter) Run() int {
 		return 1
 	}
 
+	switch i.args[1] {
+	case "run":
+		return i.runCommand(i.args[2:])
+	case "cover":
+		return i.coverCommand(i.args[2:])
+	case "check":
+		return i.checkCommand(i.args[2:])
+	case "lint":
+		return i.lintCommand(i.args[2:])
+	case "cfg":
+		return i.cfgCommand(i.args[2:])
+	case "decompile":
+		return i.decompileCommand(i.args[2:])
+	case "symex":
+		return i.symexCommand(i.args[2:])
+	case "analyze":
+		return i.analyzeCommand(i.args[2:])
+	case "fmt":
+		return i.fmtCommand(i.args[2:])
+	case "minify":
+		return i.minifyCommand(i.args[2:])
+	case "embed":
+		return i.embedCommand(i.args[2:])
+	case "extract":
+		return i.extractCommand(i.args[2:])
+	case "convert":
+		return i.convertCommand(i.args[2:])
+	case "build":
+		return i.buildCommand(i.args[2:])
+	case "dump":
+		return i.dumpCommand(i.args[2:])
+	case "batch":
+		return i.batchCommand(i.args[2:])
+	case "resume":
+		return i.resumeCommand(i.args[2:])
+	case "repl":
+		NewREPL(os.Stdin, os.Stdout).Run()
+		return 0
+	}
+
 	flag.Parse()
 	if *versionOpt {
 		fmt.Printf("ws version %s\n", version)
 		return 1
 	}
 
-	filename := i.args[1]
+	return i.runCommand(i.args[1:])
+}
+
+func (i *Interpreter) runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	profileOpt := flags.Bool("profile", false, "print an execution profile to stderr after the program ends")
+	profileOutOpt := flags.String("profile-out", "", "write a pprof compatible profile to `FILE`")
+	foldedOutOpt := flags.String("profile-folded", "", "write folded stacks for flame graph tools to `FILE`")
+	coverageOpt := flags.String("coverage", "", "record coverage into `FILE`, adding to the counts already in it")
+	var watchOpt, breakOpt watchpointFlags
+	flags.Var(&watchOpt, "watch", "log when `WATCHPOINT` triggers (write:ADDR, read:ADDR, access:ADDR, stack>N, stack<N, calls:N); repeatable")
+	flags.Var(&breakOpt, "break", "stop the program when `WATCHPOINT` triggers; same forms as -watch, repeatable")
+	snapshotOpt := flags.String("snapshot-on-error", "", "write the executor state to `FILE` when the program fails or is interrupted")
+	timeoutOpt := flags.Duration("timeout", 0, "stop the program after `DURATION`, such as 10s or 1m")
+	limits := addLimitFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of run:\n  ws run [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	if !i.parse(filename) {
+		return 1
+	}
+
+	i.executor = Executor{instructions: i.parser.Instructions}
+	i.executor.SetLimits(*limits)
+	recorder := i.recordSnapshots(*snapshotOpt)
+
+	var profiler *Profiler
+	if *profileOpt || *profileOutOpt != "" || *foldedOutOpt != "" {
+		profiler = NewProfiler(filename, i.parser.Instructions)
+		i.executor.AddHook(profiler)
+	}
+
+	var coverage *Coverage
+	if *coverageOpt != "" {
+		coverage = NewCoverage(i.parser.Instructions)
+		i.executor.AddHook(coverage)
+	}
+
+	if len(watchOpt) > 0 || len(breakOpt) > 0 {
+		for n := range breakOpt {
+			breakOpt[n].Break = true
+		}
+		watchpoints := append(append([]Watchpoint{}, watchOpt...), breakOpt...)
+		i.executor.AddHook(NewWatcher(watchpoints, i.parser.Positions, i.stderr))
+	}
+
+	ctx, cancel := withTimeout(*timeoutOpt)
+	defer cancel()
+
+	status := 0
+	errRuntime := i.executor.RunContext(ctx)
+	if errRuntime != nil {
+		fmt.Fprintln(i.stderr, i.describeError(errRuntime))
+		i.writeSnapshot(*snapshotOpt, recorder)
+		status = 1
+	}
+
+	if profiler != nil {
+		if *profileOpt {
+			profiler.WriteReport(i.stderr)
+		}
+		if *profileOutOpt != "" && !i.writeFile(*profileOutOpt, profiler.WritePprof) {
+			status = 1
+		}
+		if *foldedOutOpt != "" && !i.writeFile(*foldedOutOpt, profiler.WriteFolded) {
+			status = 1
+		}
+	}
+
+	if coverage != nil && !i.writeCoverage(*coverageOpt, coverage) {
+		status = 1
+	}
+
+	return status
+}
+
+func (i *Interpreter) resumeCommand(args []string) int {
+	flags := flag.NewFlagSet("resume", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	skipInputOpt := flags.Bool("skip-input", true, "skip the part of standard input the snapshot already consumed")
+	snapshotOpt := flags.String("snapshot-on-error", "", "write the executor state to `FILE` when the program fails or is interrupted")
+	timeoutOpt := flags.Duration("timeout", 0, "stop the program after `DURATION`, such as 10s or 1m")
+	limits := addLimitFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of resume:\n  ws resume [OPTIONS] SNAPSHOT FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 1
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not read\n", flags.Arg(0))
+		return 1
+	}
+	snapshot, err := ReadSnapshot(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s: %s\n", flags.Arg(0), err.Error())
+		return 1
+	}
+
+	if !i.parse(flags.Arg(1)) {
+		return 1
+	}
+
+	if *skipInputOpt {
+		if err := SkipInput(os.Stdin, snapshot); err != nil {
+			fmt.Fprintf(i.stderr, "can not skip consumed input: %s\n", err.Error())
+			return 1
+		}
+	}
+
+	i.executor = Executor{instructions: i.parser.Instructions}
+	i.executor.SetInput(os.Stdin)
+	i.executor.SetLimits(*limits)
+	recorder := i.recordSnapshots(*snapshotOpt)
+
+	ctx, cancel := withTimeout(*timeoutOpt)
+	defer cancel()
+
+	if err := i.executor.ResumeContext(ctx, snapshot); err != nil {
+		fmt.Fprintln(i.stderr, i.describeError(err))
+		i.writeSnapshot(*snapshotOpt, recorder)
+		return 1
+	}
+
+	return 0
+}
+
+// addLimitFlags registers a flag for every field of Limits.
+func addLimitFlags(flags *flag.FlagSet) *Limits {
+	limits := &Limits{}
+	flags.IntVar(&limits.MaxSteps, "max-steps", 0, "stop the program after `N` instructions")
+	flags.IntVar(&limits.MaxStackSize, "max-stack", 0, "stop the program when the stack holds more than `N` values")
+	flags.IntVar(&limits.MaxCallDepth, "max-call-depth", 0, "stop the program when more than `N` calls are nested")
+	flags.IntVar(&limits.MaxHeapCells, "max-heap", 0, "stop the program when it stores to more than `N` heap addresses")
+	flags.IntVar(&limits.MaxMagnitude, "max-magnitude", 0, "stop the program when a number is larger than `N` or smaller than -N")
+	flags.IntVar(&limits.MaxOutputBytes, "max-output", 0, "stop the program before it writes more than `N` bytes")
+	return limits
+}
+
+// withTimeout returns the context of a run limited to timeout, or not
+// limited when timeout is 0.
+func withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// recordSnapshots installs a SnapshotRecorder when filename is set and
+// interrupts the program on SIGINT so its state can be saved.
+func (i *Interpreter) recordSnapshots(filename string) *SnapshotRecorder {
+	if filename == "" {
+		return nil
+	}
+
+	recorder := &SnapshotRecorder{}
+	i.executor.AddHook(recorder)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		recorder.Interrupt()
+	}()
+
+	return recorder
+}
+
+func (i *Interpreter) writeSnapshot(filename string, recorder *SnapshotRecorder) {
+	if recorder == nil {
+		return
+	}
+
+	write := func(w io.Writer) error {
+		return WriteSnapshot(w, recorder.Snapshot(&i.executor))
+	}
+	if i.writeFile(filename, write) {
+		fmt.Fprintf(i.stderr, "snapshot written to %s\n", filename)
+	}
+}
+
+func (i *Interpreter) checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of check:\n  ws check [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	if !i.parse(filename) {
+		return 1
+	}
+
+	issues := CheckStack(i.parser.Instructions)
+	i.printIssues(filename, issues)
+
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (i *Interpreter) lintCommand(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	enableOpt := flags.String("enable", strings.Join(DefaultLintRules(), ","), "comma separated `RULES` to run")
+	disableOpt := flags.String("disable", "", "comma separated `RULES` to skip")
+	formatOpt := flags.String("format", "text", "output `FORMAT`, text or json")
+	listOpt := flags.Bool("list-rules", false, "list the available rules and exit")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of lint:\n  ws lint [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if *listOpt {
+		for _, rule := range LintRules {
+			fmt.Printf("%-22s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		return 0
+	}
+
+	if flags.NArg() != 1 || (*formatOpt != "text" && *formatOpt != "json") {
+		flags.Usage()
+		return 1
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range strings.Split(*disableOpt, ",") {
+		disabled[strings.TrimSpace(name)] = true
+	}
+	var rules []string
+	for _, name := range strings.Split(*enableOpt, ",") {
+		if name = strings.TrimSpace(name); name != "" && !disabled[name] {
+			rules = append(rules, name)
+		}
+	}
+
+	filename := flags.Arg(0)
+	if !i.parse(filename) {
+		return 1
+	}
+
+	issues, err := Lint(i.parser.Instructions, rules)
+	if err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+
+	if *formatOpt == "json" {
+		i.printIssuesJSON(filename, issues)
+	} else {
+		i.printIssues(filename, issues)
+	}
+
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (i *Interpreter) cfgCommand(args []string) int {
+	flags := flag.NewFlagSet("cfg", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	formatOpt := flags.String("format", "dot", "output `FORMAT`, dot or mermaid")
+	callsOpt := flags.Bool("calls", false, "write the call graph instead of the control-flow graph")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of cfg:\n  ws cfg [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	if !i.parse(flags.Arg(0)) {
+		return 1
+	}
+
+	cfg := NewControlFlowGraph(i.parser.Instructions)
+	var write func(io.Writer) error
+	switch {
+	case *formatOpt == "dot" && *callsOpt:
+		write = cfg.WriteCallGraphDOT
+	case *formatOpt == "dot":
+		write = cfg.WriteDOT
+	case *formatOpt == "mermaid" && *callsOpt:
+		write = cfg.WriteCallGraphMermaid
+	case *formatOpt == "mermaid":
+		write = cfg.WriteMermaid
+	default:
+		flags.Usage()
+		return 1
+	}
+
+	if err := write(os.Stdout); err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) decompileCommand(args []string) int {
+	flags := flag.NewFlagSet("decompile", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of decompile:\n  ws decompile [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	if !i.parse(flags.Arg(0)) {
+		return 1
+	}
+
+	if err := NewDecompiler(i.parser.Instructions).WritePseudocode(os.Stdout); err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	writeOpt := flags.Bool("w", false, "write the result to FILE instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of fmt:\n  ws fmt [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not read\n", filename)
+		return 1
+	}
+
+	notation, ok := i.notation(filename)
+	if !ok {
+		return 1
+	}
+	options := i.parseOptions
+	options.Notation = notation
+
+	formatted, err := Format(filename, string(bytes), options)
+	if err != nil {
+		fmt.Fprintln(i.stderr, RenderError(err))
+		return 1
+	}
+
+	if !*writeOpt {
+		fmt.Print(formatted)
+		return 0
+	}
+	if err := ioutil.WriteFile(filename, []byte(formatted), 0644); err != nil {
+		fmt.Fprintf(i.stderr, "%s can not write\n", filename)
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) minifyCommand(args []string) int {
+	flags := flag.NewFlagSet("minify", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	outOpt := flags.String("o", "", "write the result to `FILE` instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of minify:\n  ws minify [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	if !i.parse(flags.Arg(0)) {
+		return 1
+	}
+
+	minified, err := Minify(i.parser.Instructions)
+	if err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+
+	return i.writeOutput(*outOpt, minified)
+}
+
+func (i *Interpreter) embedCommand(args []string) int {
+	flags := flag.NewFlagSet("embed", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	carrierOpt := flags.String("carrier", "", "hide the program in the text of `FILE`")
+	outOpt := flags.String("o", "", "write the result to `FILE` instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of embed:\n  ws embed --carrier CARRIER [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 || *carrierOpt == "" {
+		flags.Usage()
+		return 1
+	}
+
+	carrier, err := ioutil.ReadFile(*carrierOpt)
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not read\n", *carrierOpt)
+		return 1
+	}
+	program, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not read\n", flags.Arg(0))
+		return 1
+	}
+
+	embedded, err := Embed(string(carrier), string(program))
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s: %s\n", flags.Arg(0), RenderError(err))
+		return 1
+	}
+	return i.writeOutput(*outOpt, embedded)
+}
+
+func (i *Interpreter) extractCommand(args []string) int {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	outOpt := flags.String("o", "", "write the program to `FILE` instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of extract:\n  ws extract [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	text, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not read\n", flags.Arg(0))
+		return 1
+	}
+	return i.writeOutput(*outOpt, Extract(string(text)))
+}
+
+func (i *Interpreter) convertCommand(args []string) int {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	fromOpt := flags.String("from", "", "read FILE written in `NOTATION` (default from the file extension)")
+	toOpt := flags.String("to", "whitespace", "write the program in `NOTATION`: whitespace, stl, bracket or SPACE,TAB,LF symbols")
+	outOpt := flags.String("o", "", "write the result to `FILE` instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of convert:\n  ws convert [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	i.notationOpt = *fromOpt
+	from, ok := i.notation(filename)
+	if !ok {
+		return 1
+	}
+	to, err := ParseNotation(*toOpt)
+	if err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not read\n", filename)
+		return 1
+	}
+	return i.writeOutput(*outOpt, to.Encode(from.Decode(string(source))))
+}
+
+func (i *Interpreter) buildCommand(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	outOpt := flags.String("o", "", "write the compiled program to `FILE` (default FILE with the extension "+BinaryExtension+")")
+	sourceMapOpt := flags.Bool("source-map", true, "keep the source positions of the instructions")
+	debugOpt := flags.Bool("debug", false, "keep the source itself along with the source map")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of build:\n  ws build [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	if !i.parse(filename) {
+		return 1
+	}
+
+	var sourceMap *SourceMap
+	if *sourceMapOpt || *debugOpt {
+		sourceMap = &SourceMap{Filename: i.parser.filename, Positions: i.parser.Positions}
+		if *debugOpt {
+			sourceMap.Source = i.parser.rawSourceCode
+		}
+	}
+
+	out := *outOpt
+	if out == "" {
+		out = strings.TrimSuffix(filename, filepath.Ext(filename)) + BinaryExtension
+	}
+	write := func(w io.Writer) error {
+		return WriteBinary(w, i.parser.Instructions, sourceMap)
+	}
+	if !i.writeFile(out, write) {
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) dumpCommand(args []string) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	formatOpt := flags.String("format", "text", "output `FORMAT`, text or json")
+	outOpt := flags.String("o", "", "write the program to `FILE` instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of dump:\n  ws dump [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 || (*formatOpt != "text" && *formatOpt != "json") {
+		flags.Usage()
+		return 1
+	}
+
+	if !i.parse(flags.Arg(0)) {
+		return 1
+	}
+
+	if *formatOpt == "text" {
+		return i.writeOutput(*outOpt, Disassemble(i.parser.Instructions))
+	}
+
+	var sourceMap *SourceMap
+	if len(i.parser.Positions) == len(i.parser.Instructions) {
+		sourceMap = &SourceMap{Filename: i.parser.filename, Positions: i.parser.Positions}
+	}
+	var builder strings.Builder
+	if err := WriteJSON(&builder, i.parser.Instructions, sourceMap); err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+	return i.writeOutput(*outOpt, builder.String())
+}
+
+func (i *Interpreter) batchCommand(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	workersOpt := flags.Int("workers", 0, "run `N` inputs at a time (default the number of CPUs)")
+	timeoutOpt := flags.Duration("timeout", 0, "stop every run after `DURATION`, such as 10s or 1m")
+	junitOpt := flags.String("junit", "", "write a JUnit XML report to `FILE`")
+	limits := addLimitFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of batch:\n  ws batch [OPTIONS] FILE INPUT...\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 1
+	}
+
+	if !i.parse(flags.Arg(0)) {
+		return 1
+	}
+
+	program := NewProgram(i.parser.Instructions)
+	options := BatchOptions{Workers: *workersOpt, Timeout: *timeoutOpt, Limits: *limits}
+	runs := RunBatch(context.Background(), program, flags.Args()[1:], options)
+
+	counts := map[BatchStatus]int{}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "status\tsteps\ttime\tinput\tdetail")
+	for n := range runs {
+		run := &runs[n]
+		if run.Err != nil {
+			run.Message = i.describeError(run.Err)
+		}
+		counts[run.Status]++
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", run.Status, run.Steps, run.Duration.Round(time.Microsecond), run.Input, run.Message)
+	}
+	tw.Flush()
+	fmt.Printf("\n%d runs: %d passed, %d failed, %d errors\n", len(runs), counts[BatchPassed], counts[BatchFailed], counts[BatchError])
 
+	if *junitOpt != "" {
+		write := func(w io.Writer) error {
+			return WriteJUnit(w, i.parser.filename, runs)
+		}
+		if !i.writeFile(*junitOpt, write) {
+			return 1
+		}
+	}
+
+	if counts[BatchPassed] != len(runs) {
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) analyzeCommand(args []string) int {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of analyze:\n  ws analyze [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	if !i.parse(flags.Arg(0)) {
+		return 1
+	}
+
+	if err := AnalyzeValues(i.parser.Instructions).WriteReport(os.Stdout); err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func (i *Interpreter) symexCommand(args []string) int {
+	flags := flag.NewFlagSet("symex", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	targetOpt := flags.String("target", "", "find inputs that reach `LABEL`, written with S and T")
+	maxPathsOpt := flags.Int("max-paths", defaultMaxPaths, "explore at most `N` paths")
+	maxStepsOpt := flags.Int("max-steps", defaultMaxSteps, "run at most `N` instructions along each path")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of symex:\n  ws symex [OPTIONS] FILE\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	if !i.parse(filename) {
+		return 1
+	}
+
+	symbolic, err := NewSymbolicExecutor(i.parser.Instructions, SymbolicOptions{Target: *targetOpt, MaxPaths: *maxPathsOpt, MaxSteps: *maxStepsOpt})
+	if err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return 1
+	}
+
+	findings := symbolic.Explore()
+	for _, finding := range findings {
+		fmt.Printf("%s:%s: %s: %s\n  input: %q\n", filename, i.location(finding.PC), finding.Kind, finding.Message, finding.InputText())
+	}
+	if symbolic.Truncated {
+		fmt.Fprintf(i.stderr, "exploration stopped early after %d paths; raise --max-paths or --max-steps to go further\n", symbolic.Paths)
+	}
+
+	for _, finding := range findings {
+		if finding.Kind != FindingTarget {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (i *Interpreter) printIssuesJSON(filename string, issues []Issue) {
+	type jsonIssue struct {
+		File        string `json:"file"`
+		Line        int    `json:"line,omitempty"`
+		Column      int    `json:"column,omitempty"`
+		PC          int    `json:"pc"`
+		Instruction string `json:"instruction"`
+		Rule        string `json:"rule"`
+		Severity    string `json:"severity"`
+		Message     string `json:"message"`
+	}
+
+	report := make([]jsonIssue, len(issues))
+	for n, issue := range issues {
+		position, _ := i.position(issue.PC)
+		report[n] = jsonIssue{
+			File:        filename,
+			Line:        position.Line,
+			Column:      position.Column,
+			PC:          issue.PC,
+			Instruction: Mnemonic(i.parser.Instructions[issue.PC]),
+			Rule:        issue.Rule,
+			Severity:    issue.Severity.String(),
+			Message:     issue.Message,
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+func (i *Interpreter) printIssues(filename string, issues []Issue) {
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stdout, "%s:%s: %s: %s [%s]\n", filename, i.location(issue.PC), issue.Severity, issue.Message, issue.Rule)
+	}
+}
+
+func (i *Interpreter) writeCoverage(filename string, coverage *Coverage) bool {
+	if file, err := os.Open(filename); err == nil {
+		previous, errRead := ReadCoverage(file, i.parser.Instructions)
+		file.Close()
+		if errRead != nil {
+			fmt.Fprintf(i.stderr, "%s: %s\n", filename, errRead.Error())
+			return false
+		}
+		if errMerge := coverage.Merge(previous); errMerge != nil {
+			fmt.Fprintf(i.stderr, "%s: %s\n", filename, errMerge.Error())
+			return false
+		}
+	}
+
+	return i.writeFile(filename, coverage.WriteProfile)
+}
+
+func (i *Interpreter) coverCommand(args []string) int {
+	flags := flag.NewFlagSet("cover", flag.ContinueOnError)
+	flags.SetOutput(i.stderr)
+	i.addParseFlags(flags)
+	lcovOpt := flags.String("lcov", "", "write the merged coverage as an LCOV tracefile to `FILE`")
+	flags.Usage = func() {
+		fmt.Fprintf(i.stderr, "Usage of cover:\n  ws cover [OPTIONS] FILE PROFILE...\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 1
+	}
+
+	filename := flags.Arg(0)
+	if !i.parse(filename) {
+		return 1
+	}
+
+	coverage := NewCoverage(i.parser.Instructions)
+	for _, profile := range flags.Args()[1:] {
+		file, err := os.Open(profile)
+		if err != nil {
+			fmt.Fprintf(i.stderr, "%s can not read\n", profile)
+			return 1
+		}
+		recorded, err := ReadCoverage(file, i.parser.Instructions)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(i.stderr, "%s: %s\n", profile, err.Error())
+			return 1
+		}
+		if err := coverage.Merge(recorded); err != nil {
+			fmt.Fprintf(i.stderr, "%s: %s\n", profile, err.Error())
+			return 1
+		}
+	}
+
+	coverage.WriteReport(os.Stdout, i.parser.Positions)
+
+	if *lcovOpt != "" {
+		write := func(w io.Writer) error {
+			return coverage.WriteLCOV(w, filename, i.parser.Positions)
+		}
+		if !i.writeFile(*lcovOpt, write) {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+type watchpointFlags []Watchpoint
+
+func (w *watchpointFlags) String() string {
+	specs := make([]string, len(*w))
+	for n, watchpoint := range *w {
+		specs[n] = watchpoint.String()
+	}
+	return strings.Join(specs, ",")
+}
+
+func (w *watchpointFlags) Set(spec string) error {
+	watchpoint, err := ParseWatchpoint(spec)
+	if err != nil {
+		return err
+	}
+	*w = append(*w, watchpoint)
+	return nil
+}
+
+// addParseFlags registers the options that control how FILE is parsed.
+func (i *Interpreter) addParseFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&i.parseOptions.Strict, "strict", false, "report characters that look like whitespace but are not tokens, such as no-break spaces and CR")
+	flags.BoolVar(&i.parseOptions.CRLF, "crlf", false, "read CR LF line endings as a single LF")
+	flags.StringVar(&i.notationOpt, "notation", "", "read tokens written in `NOTATION`: whitespace, stl, bracket or SPACE,TAB,LF symbols (default from the file extension)")
+}
+
+// notation returns the notation of filename chosen with -notation or by its
+// extension.
+func (i *Interpreter) notation(filename string) (Notation, bool) {
+	if isProgramFile(filename) {
+		fmt.Fprintf(i.stderr, "%s is a parsed program, not a source\n", filename)
+		return Notation{}, false
+	}
+	if i.notationOpt == "" {
+		return NotationForFile(filename), true
+	}
+
+	notation, err := ParseNotation(i.notationOpt)
+	if err != nil {
+		fmt.Fprintln(i.stderr, err.Error())
+		return Notation{}, false
+	}
+	return notation, true
+}
+
+func (i *Interpreter) parse(filename string) bool {
 	bytes, errReadFile := ioutil.ReadFile(filename)
 	if errReadFile != nil {
 		fmt.Fprintf(i.stderr, "%s can not read\n", filename)
-		return 1
+		return false
+	}
+
+	if isProgramFile(filename) {
+		return i.load(filename, bytes)
+	}
+
+	notation, ok := i.notation(filename)
+	if !ok {
+		return false
 	}
 
 	i.parser = NewParser(filename, string(bytes))
+	i.parser.Options = i.parseOptions
+	i.parser.Options.Notation = notation
 	errParse := i.parser.ParseAll()
 	if errParse != nil {
-		fmt.Fprintln(i.stderr, errParse.Error())
-		return 1
+		fmt.Fprintln(i.stderr, RenderError(errParse))
+		return false
 	}
 
-	i.executor = Executor{instructions: i.parser.Instructions}
-	errRuntime := i.executor.Run()
-	if errRuntime != nil {
-		fmt.Fprintln(i.stderr, errRuntime.Error())
-		return 1
+	return true
+}
+
+// programReaders read the files holding an already parsed program, by
+// extension.
+var programReaders = map[string]func(io.Reader) ([]Instruction, *SourceMap, error){
+	BinaryExtension: ReadBinary,
+	JSONExtension:   ReadJSON,
+}
+
+func isProgramFile(filename string) bool {
+	_, ok := programReaders[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// load reads a compiled or JSON program into the parser, keeping the source
+// file name and positions when it has a source map.
+func (i *Interpreter) load(filename string, data []byte) bool {
+	read := programReaders[strings.ToLower(filepath.Ext(filename))]
+	instructions, sourceMap, err := read(strings.NewReader(string(data)))
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s: %s\n", filename, err.Error())
+		return false
+	}
+
+	source := ""
+	if sourceMap != nil {
+		filename, source = sourceMap.Filename, sourceMap.Source
+	}
+	i.parser = NewParser(filename, source)
+	i.parser.Instructions = instructions
+	if sourceMap != nil {
+		i.parser.Positions = sourceMap.Positions
+	}
+	return true
+}
+
+// describeError adds the source position of the failing instruction to
+// runtime, limit and watchpoint errors, and of the next instruction to
+// cancellations. A
+// loaded program reports the source it was built from.
+func (i *Interpreter) describeError(err error) string {
+	pc := -1
+	switch err := err.(type) {
+	case *RuntimeError:
+		pc = err.PC
+	case *CanceledError:
+		pc = err.PC
+	case *LimitError:
+		pc = err.PC
+	case *WatchpointError:
+		pc = err.PC
+	}
+
+	if position, ok := i.position(pc); ok {
+		return fmt.Sprintf("%s at %s:%s", err.Error(), i.parser.filename, position)
+	}
+	return err.Error()
+}
+
+// position returns the source position of the instruction at pc. Programs
+// loaded without a source map have none.
+func (i *Interpreter) position(pc int) (Position, bool) {
+	return positionAt(i.parser.Positions, pc)
+}
+
+// location formats the source position of the instruction at pc, or the pc
+// itself when there is no position.
+func (i *Interpreter) location(pc int) string {
+	if position, ok := i.position(pc); ok {
+		return position.String()
+	}
+	return fmt.Sprintf("pc %d", pc)
+}
+
+// writeOutput writes text to filename, or to standard output when filename
+// is empty.
+func (i *Interpreter) writeOutput(filename string, text string) int {
+	if filename == "" {
+		fmt.Print(text)
+		return 0
 	}
 
+	write := func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	}
+	if !i.writeFile(filename, write) {
+		return 1
+	}
 	return 0
 }
+
+func (i *Interpreter) writeFile(filename string, write func(io.Writer) error) bool {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Fprintf(i.stderr, "%s can not write\n", filename)
+		return false
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		fmt.Fprintf(i.stderr, "%s can not write: %s\n", filename, err.Error())
+		return false
+	}
+
+	return true
+}
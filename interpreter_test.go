@@ -0,0 +1,73 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// captureStdout returns what run writes to standard output.
+func captureStdout(t *testing.T, run func()) string {
+	file, err := ioutil.TempFile("", "stdout")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	stdout := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = stdout }()
+	run()
+
+	output, err := ioutil.ReadFile(file.Name())
+	assert.Nil(t, err)
+	return string(output)
+}
+
+func TestLintProgramWithoutPositions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	instructions, err := Assemble("push 1 jump S push 2 label S end")
+	assert.Nil(t, err)
+	var program bytes.Buffer
+	assert.Nil(t, WriteJSON(&program, instructions, nil))
+	filename := filepath.Join(dir, "program.json")
+	assert.Nil(t, ioutil.WriteFile(filename, program.Bytes(), 0644))
+
+	var stderr bytes.Buffer
+	status := 0
+	output := captureStdout(t, func() {
+		status = (&Interpreter{args: []string{"ws", "lint", filename}, stderr: &stderr}).Run()
+	})
+	assert.Equal(t, 0, status)
+	assert.Equal(t, filename+":pc 2: warning: instructions 2 to 2 are unreachable [unreachable-code]\n", output)
+	assert.Empty(t, stderr.String())
+
+	output = captureStdout(t, func() {
+		status = (&Interpreter{args: []string{"ws", "lint", "--format", "json", filename}, stderr: &stderr}).Run()
+	})
+	assert.Equal(t, 0, status)
+	assert.Contains(t, output, `"pc": 2,`)
+	assert.NotContains(t, output, `"line"`)
+}
+
+func TestRunBreakReportsPosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "break")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "program.ws")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("   \t\n   \t \n\n\n\n"), 0644))
+
+	var stderr bytes.Buffer
+	status := 0
+	captureStdout(t, func() {
+		status = (&Interpreter{args: []string{"ws", "run", "--break", "stack>1", filename}, stderr: &stderr}).Run()
+	})
+	assert.Equal(t, 1, status)
+	assert.Equal(t, "Watchpoint stack>1: stack depth 2 exceeds 1 at instruction 1 (push 2) at "+filename+":2:1\n", stderr.String())
+}
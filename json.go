@@ -0,0 +1,176 @@
+package whitespace_go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONExtension is the extension of programs written by WriteJSON.
+const JSONExtension = ".json"
+
+// JSONProgram is the JSON form of a parsed program, for tools that do not
+// read Whitespace themselves. File is the source the positions refer to.
+type JSONProgram struct {
+	File         string            `json:"file,omitempty"`
+	Instructions []JSONInstruction `json:"instructions"`
+}
+
+// JSONInstruction is one instruction of a JSONProgram. Kind is the mnemonic
+// of its command, such as "push" or "jz". Push has an Operand, and the
+// commands taking a label have Label, the label as a string of bits with 1
+// for a tab. Name is the label written with S and T, as in mnemonics; it is
+// only informative and ignored by ReadJSON.
+type JSONInstruction struct {
+	Kind     string        `json:"kind"`
+	Operand  *int          `json:"operand,omitempty"`
+	Label    *string       `json:"label,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Position *JSONPosition `json:"position,omitempty"`
+}
+
+// JSONPosition is the Position of an instruction in its source.
+type JSONPosition struct {
+	Offset int `json:"offset"`
+	End    int `json:"end"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// WriteJSON writes instructions as an indented JSONProgram. Positions are
+// left out when sourceMap is nil.
+func WriteJSON(w io.Writer, instructions []Instruction, sourceMap *SourceMap) error {
+	program := JSONProgram{Instructions: make([]JSONInstruction, len(instructions))}
+	if sourceMap != nil {
+		if len(sourceMap.Positions) != len(instructions) {
+			return fmt.Errorf("the source map does not match the instructions")
+		}
+		program.File = sourceMap.Filename
+	}
+
+	for pc, instruction := range instructions {
+		command := commandOf(instruction)
+		encoded := JSONInstruction{Kind: command.name}
+		switch command.parameter {
+		case numberParameter:
+			value := instruction.(Push).value
+			encoded.Operand = &value
+		case labelParameter:
+			label := instructionLabel(instruction)
+			bits := strings.NewReplacer(SPACE, "0", TAB, "1").Replace(label)
+			encoded.Label = &bits
+			encoded.Name = LabelName(label)
+		}
+		if sourceMap != nil {
+			position := sourceMap.Positions[pc]
+			encoded.Position = &JSONPosition{Offset: position.Offset, End: position.End, Line: position.Line, Column: position.Column}
+		}
+		program.Instructions[pc] = encoded
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(program)
+}
+
+// ReadJSON reads a program written by WriteJSON, or by any tool following
+// JSONProgram. Either every instruction has a position or none does; the
+// source map is nil in the latter case.
+func ReadJSON(r io.Reader) ([]Instruction, *SourceMap, error) {
+	var program JSONProgram
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&program); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON program: %s", err.Error())
+	}
+
+	instructions := make([]Instruction, len(program.Instructions))
+	positions := make([]Position, 0, len(program.Instructions))
+	for pc, encoded := range program.Instructions {
+		instruction, err := decodeJSONInstruction(encoded)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON program: instruction %d: %s", pc, err.Error())
+		}
+		instructions[pc] = instruction
+
+		if (encoded.Position != nil) != (program.Instructions[0].Position != nil) {
+			return nil, nil, fmt.Errorf("invalid JSON program: instruction %d: positions must be given for every instruction or none", pc)
+		}
+		if position := encoded.Position; position != nil {
+			if err := position.validate(); err != nil {
+				return nil, nil, fmt.Errorf("invalid JSON program: instruction %d: %s", pc, err.Error())
+			}
+			positions = append(positions, Position{Offset: position.Offset, End: position.End, Line: position.Line, Column: position.Column})
+		}
+	}
+
+	if len(positions) == 0 {
+		return instructions, nil, nil
+	}
+	return instructions, &SourceMap{Filename: program.File, Positions: positions}, nil
+}
+
+func (position *JSONPosition) validate() error {
+	switch {
+	case position.Offset < 0 || position.End < position.Offset:
+		return fmt.Errorf("invalid position offsets %d to %d", position.Offset, position.End)
+	case position.Line < 1 || position.Column < 1:
+		return fmt.Errorf("invalid position line %d, column %d", position.Line, position.Column)
+	}
+	return nil
+}
+
+func decodeJSONInstruction(encoded JSONInstruction) (Instruction, error) {
+	var command *command
+	for n := range commands {
+		if commands[n].name == encoded.Kind {
+			command = &commands[n]
+		}
+	}
+	if command == nil {
+		return nil, fmt.Errorf("unknown kind %q", encoded.Kind)
+	}
+
+	if (encoded.Operand != nil) != (command.parameter == numberParameter) {
+		return nil, fmt.Errorf("%s takes %s", command.name, operandName(command.parameter))
+	}
+	if (encoded.Label != nil) != (command.parameter == labelParameter) {
+		return nil, fmt.Errorf("%s takes %s", command.name, operandName(command.parameter))
+	}
+
+	switch command.parameter {
+	case numberParameter:
+		return Push{value: *encoded.Operand}, nil
+	case labelParameter:
+		if *encoded.Label == "" {
+			return nil, fmt.Errorf("%s takes a label of at least one bit", command.name)
+		}
+		var label strings.Builder
+		for _, bit := range *encoded.Label {
+			switch bit {
+			case '0':
+				label.WriteString(SPACE)
+			case '1':
+				label.WriteString(TAB)
+			default:
+				return nil, fmt.Errorf("invalid label %q, labels are written with 0 and 1", *encoded.Label)
+			}
+		}
+		return labelInstruction(command.name, label.String()), nil
+	default:
+		instruction, _ := assembleNullary(command.name)
+		return instruction, nil
+	}
+}
+
+func operandName(parameter parameterKind) string {
+	switch parameter {
+	case numberParameter:
+		return "an operand"
+	case labelParameter:
+		return "a label"
+	default:
+		return "no operand or label"
+	}
+}
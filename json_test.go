@@ -0,0 +1,81 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	parser := NewParser("binary.ws", binarySource)
+	assert.Nil(t, parser.ParseAll())
+
+	var builder strings.Builder
+	assert.Nil(t, WriteJSON(&builder, parser.Instructions, &SourceMap{Filename: "binary.ws", Positions: parser.Positions}))
+	assert.Contains(t, builder.String(), `"kind": "jn",
+      "label": "101100110",
+      "name": "TSTTSSTTS",`)
+
+	instructions, sourceMap, err := ReadJSON(strings.NewReader(builder.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, parser.Instructions, instructions)
+	assert.Equal(t, &SourceMap{Filename: "binary.ws", Positions: parser.Positions}, sourceMap)
+
+	builder.Reset()
+	assert.Nil(t, WriteJSON(&builder, parser.Instructions, nil))
+	assert.NotContains(t, builder.String(), "position")
+	instructions, sourceMap, err = ReadJSON(strings.NewReader(builder.String()))
+	assert.Nil(t, err)
+	assert.Equal(t, parser.Instructions, instructions)
+	assert.Nil(t, sourceMap)
+}
+
+func TestReadJSONFromOtherTools(t *testing.T) {
+	program := `{"instructions": [
+		{"kind": "push", "operand": -3},
+		{"kind": "label", "label": "1"},
+		{"kind": "call", "label": "01"},
+		{"kind": "end"}
+	]}`
+
+	instructions, sourceMap, err := ReadJSON(strings.NewReader(program))
+	assert.Nil(t, err)
+	assert.Nil(t, sourceMap)
+	assert.Equal(t, []Instruction{Push{value: -3}, MarkLabel{label: TAB}, CallSubroutine{label: SPACE + TAB}, EndProgram{}}, instructions)
+}
+
+func TestReadJSONRejectsInvalidInstructions(t *testing.T) {
+	read := func(instruction string) error {
+		_, _, err := ReadJSON(strings.NewReader(`{"instructions": [` + instruction + `]}`))
+		return err
+	}
+
+	assert.EqualError(t, read(`{"kind": "nop"}`), `invalid JSON program: instruction 0: unknown kind "nop"`)
+	assert.EqualError(t, read(`{"kind": "push"}`), "invalid JSON program: instruction 0: push takes an operand")
+	assert.EqualError(t, read(`{"kind": "jump", "operand": 1}`), "invalid JSON program: instruction 0: jump takes a label")
+	assert.EqualError(t, read(`{"kind": "dup", "label": "0"}`), "invalid JSON program: instruction 0: dup takes no operand or label")
+	assert.EqualError(t, read(`{"kind": "label", "label": ""}`), "invalid JSON program: instruction 0: label takes a label of at least one bit")
+	assert.EqualError(t, read(`{"kind": "jz", "label": "ST"}`), `invalid JSON program: instruction 0: invalid label "ST", labels are written with 0 and 1`)
+	assert.EqualError(t, read(`{"kind": "dup", "args": []}`), `invalid JSON program: json: unknown field "args"`)
+}
+
+func TestReadJSONRejectsInvalidPositions(t *testing.T) {
+	read := func(instructions string) error {
+		_, _, err := ReadJSON(strings.NewReader(`{"instructions": [` + instructions + `]}`))
+		return err
+	}
+	position := func(offset, end, line, column int) string {
+		return fmt.Sprintf(`{"kind": "end", "position": {"offset": %d, "end": %d, "line": %d, "column": %d}}`, offset, end, line, column)
+	}
+
+	assert.EqualError(t, read(position(0, 3, 1, 1)+`, {"kind": "end"}`),
+		"invalid JSON program: instruction 1: positions must be given for every instruction or none")
+	assert.EqualError(t, read(`{"kind": "end"}, `+position(0, 3, 1, 1)),
+		"invalid JSON program: instruction 1: positions must be given for every instruction or none")
+	assert.EqualError(t, read(position(3, 2, 1, 1)), "invalid JSON program: instruction 0: invalid position offsets 3 to 2")
+	assert.EqualError(t, read(position(-1, 2, 1, 1)), "invalid JSON program: instruction 0: invalid position offsets -1 to 2")
+	assert.EqualError(t, read(position(0, 3, 0, 1)), "invalid JSON program: instruction 0: invalid position line 0, column 1")
+	assert.EqualError(t, read(position(0, 3, 1, 0)), "invalid JSON program: instruction 0: invalid position line 1, column 0")
+	assert.Nil(t, read(position(0, 3, 1, 1)+", "+position(3, 3, 1, 4)))
+}
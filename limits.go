@@ -0,0 +1,83 @@
+package whitespace_go
+
+import "fmt"
+
+// Limits bounds the resources a run may use, for running programs that are
+// not trusted. A zero field sets no limit.
+type Limits struct {
+	// MaxSteps is the number of instructions executed.
+	MaxSteps int
+	// MaxStackSize is the number of values on the stack.
+	MaxStackSize int
+	// MaxCallDepth is the number of calls not returned from yet.
+	MaxCallDepth int
+	// MaxHeapCells is the number of heap addresses stored to.
+	MaxHeapCells int
+	// MaxMagnitude is the largest absolute value of a number pushed or
+	// stored.
+	MaxMagnitude int
+	// MaxOutputBytes is the number of bytes putc and putn write.
+	MaxOutputBytes int
+}
+
+// Limit names one of the fields of Limits.
+type Limit int
+
+const (
+	StepLimit Limit = iota
+	StackSizeLimit
+	CallDepthLimit
+	HeapCellsLimit
+	MagnitudeLimit
+	OutputBytesLimit
+)
+
+func (limit Limit) String() string {
+	switch limit {
+	case StepLimit:
+		return "steps"
+	case StackSizeLimit:
+		return "stack size"
+	case CallDepthLimit:
+		return "call depth"
+	case HeapCellsLimit:
+		return "heap cells"
+	case MagnitudeLimit:
+		return "number magnitude"
+	case OutputBytesLimit:
+		return "output bytes"
+	default:
+		return fmt.Sprintf("Limit(%d)", int(limit))
+	}
+}
+
+// SetLimits bounds the resources the following runs may use.
+func (executor *Executor) SetLimits(limits Limits) {
+	executor.limits = limits
+}
+
+func limitError(executor *Executor, limit Limit, max int) error {
+	return &LimitError{PC: executor.programCounter, Limit: limit, Max: max}
+}
+
+// exceeds reports whether the limit max is set and n is above it.
+func exceeds(n int, max int) bool {
+	return max > 0 && n > max
+}
+
+func (executor *Executor) checkMagnitude(value int) error {
+	max := executor.limits.MaxMagnitude
+	if max > 0 && (value > max || value < -max) {
+		return limitError(executor, MagnitudeLimit, max)
+	}
+	return nil
+}
+
+// overflowError reports an arithmetic result that does not fit in an int. It
+// is above any magnitude limit, and a runtime error when there is none.
+func (executor *Executor) overflowError() error {
+	if max := executor.limits.MaxMagnitude; max > 0 {
+		return limitError(executor, MagnitudeLimit, max)
+	}
+	return runtimeError(executor, "integer overflow")
+}
@@ -0,0 +1,94 @@
+package whitespace_go
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func runLimited(t *testing.T, assembly string, limits Limits, input string) (*Result, error) {
+	return compileAssembly(t, assembly).Run(context.Background(), WithLimits(limits), WithInput(strings.NewReader(input)))
+}
+
+func TestStackSizeLimit(t *testing.T) {
+	_, err := runLimited(t, "push 1 push 2 push 3 end", Limits{MaxStackSize: 3}, "")
+	assert.Nil(t, err)
+
+	_, err = runLimited(t, "push 1 push 2 push 3 dup end", Limits{MaxStackSize: 3}, "")
+	assert.Equal(t, &LimitError{PC: 3, Limit: StackSizeLimit, Max: 3}, err)
+	assert.EqualError(t, err, "Limit exceeded: stack size above 3")
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	_, err := runLimited(t, "label S call S", Limits{MaxCallDepth: 100}, "")
+	assert.Equal(t, &LimitError{PC: 1, Limit: CallDepthLimit, Max: 100}, err)
+}
+
+func TestHeapCellsLimit(t *testing.T) {
+	_, err := runLimited(t, "push 0 push 1 store push 0 push 2 store push 1 getn end", Limits{MaxHeapCells: 1}, "3\n")
+	assert.Equal(t, &LimitError{PC: 7, Limit: HeapCellsLimit, Max: 1}, err)
+
+	_, err = runLimited(t, "push 0 getc push 1 push 0 retrieve store end", Limits{MaxHeapCells: 2}, "a\n")
+	assert.Nil(t, err)
+}
+
+func TestMagnitudeLimit(t *testing.T) {
+	_, err := runLimited(t, "push 1000 push -1000 end", Limits{MaxMagnitude: 1000}, "")
+	assert.Nil(t, err)
+
+	_, err = runLimited(t, "push 1000 push 1000 add end", Limits{MaxMagnitude: 1000}, "")
+	assert.Equal(t, &LimitError{PC: 2, Limit: MagnitudeLimit, Max: 1000}, err)
+
+	_, err = runLimited(t, "push -1001", Limits{MaxMagnitude: 1000}, "")
+	assert.Equal(t, &LimitError{PC: 0, Limit: MagnitudeLimit, Max: 1000}, err)
+
+	_, err = runLimited(t, "push 0 getn", Limits{MaxMagnitude: 1000}, "-5000\n")
+	assert.Equal(t, &LimitError{PC: 1, Limit: MagnitudeLimit, Max: 1000}, err)
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	result, err := runLimited(t, "push 4294967296 dup mul putn end", Limits{MaxMagnitude: 1 << 40}, "")
+	assert.Equal(t, &LimitError{PC: 2, Limit: MagnitudeLimit, Max: 1 << 40}, err)
+	assert.Equal(t, "", result.Output)
+
+	// push -9223372036854775807 push 1 swap sub pushes the smallest int.
+	for _, assembly := range []string{
+		"push 4294967296 dup mul end",
+		"push 9223372036854775807 push 1 add end",
+		"push -1 push 9223372036854775807 sub end",
+		"push -9223372036854775807 push 1 swap sub push 1 swap sub end",
+		"push -9223372036854775807 push 1 swap sub push -1 mul end",
+		"push -1 push -9223372036854775807 push 1 swap sub mul end",
+		"push -1 push -9223372036854775807 push 1 swap sub div end",
+	} {
+		_, err = runLimited(t, assembly, Limits{}, "")
+		assert.EqualError(t, err, "Runtime error: integer overflow", assembly)
+	}
+
+	result, err = runLimited(t, "push -9223372036854775807 push 1 swap sub putn end", Limits{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "-9223372036854775808", result.Output)
+}
+
+func TestOutputBytesLimit(t *testing.T) {
+	result, err := runLimited(t, "push 12 putn push 345 putn", Limits{MaxOutputBytes: 4}, "")
+	assert.Equal(t, &LimitError{PC: 3, Limit: OutputBytesLimit, Max: 4}, err)
+	assert.Equal(t, "12", result.Output)
+
+	result, err = runLimited(t, "push 233 putc push 65 putc", Limits{MaxOutputBytes: 3}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "éA", result.Output)
+}
+
+func TestLimitsReset(t *testing.T) {
+	executor := &Executor{instructions: []Instruction{Push{value: 7}, Putn{}}}
+	var output strings.Builder
+	executor.SetOutput(&output)
+	executor.SetLimits(Limits{MaxSteps: 2, MaxOutputBytes: 1})
+
+	assert.Nil(t, executor.Run())
+	executor.stack = nil
+	assert.Nil(t, executor.Run())
+	assert.Equal(t, "77", output.String())
+}
@@ -0,0 +1,345 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LintRule is one check run by Lint.
+type LintRule struct {
+	Name        string
+	Severity    Severity
+	Description string
+	check       func(linter *linter)
+}
+
+// LintRules lists every rule Lint knows, in the order they run.
+var LintRules = []LintRule{
+	{"undefined-label", SeverityError, "jump or call to a label that is never marked", lintUndefinedLabels},
+	{"duplicate-label", SeverityWarning, "label marked more than once; only the first mark is ever jumped to", lintDuplicateLabels},
+	{"unused-label", SeverityWarning, "label that no jump or call refers to", lintUnusedLabels},
+	{"unreachable-code", SeverityWarning, "instructions that can never run", lintUnreachableCode},
+	{"missing-end", SeverityWarning, "execution can fall off the end of the program without end", lintMissingEnd},
+	{"ret-outside-call", SeverityError, "ret reachable without a call, which fails with an empty call stack", lintReturnOutsideCall},
+	{"division-by-zero", SeverityError, "div or mod whose divisor is the literal zero", lintDivisionByZero},
+	{"retrieve-never-stored", SeverityWarning, "retrieve from a literal address that is never stored to", lintRetrieveNeverStored},
+	{"uninitialized-retrieve", SeverityWarning, "retrieve from an address that may not be stored to before it", lintUninitializedRetrieve},
+	{"invalid-code-point", SeverityWarning, "putc of a value that may not be a Unicode code point", lintInvalidCodePoint},
+}
+
+// DefaultLintRules returns the names of every rule in LintRules.
+func DefaultLintRules() []string {
+	names := make([]string, len(LintRules))
+	for i, rule := range LintRules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+type linter struct {
+	instructions []Instruction
+	labels       map[string]int
+	rule         LintRule
+	issues       []Issue
+	values       *ValueAnalysis
+}
+
+// valueAnalysis returns the value ranges of the program, which are computed
+// once for all the rules that need them.
+func (linter *linter) valueAnalysis() *ValueAnalysis {
+	if linter.values == nil {
+		linter.values = AnalyzeValues(linter.instructions)
+	}
+	return linter.values
+}
+
+func (linter *linter) report(pc int, message string) {
+	linter.issues = append(linter.issues, Issue{Rule: linter.rule.Name, Severity: linter.rule.Severity, PC: pc, Message: message})
+}
+
+// Lint runs the named rules over instructions. Unknown rule names are an
+// error.
+func Lint(instructions []Instruction, rules []string) ([]Issue, error) {
+	enabled := map[string]bool{}
+	for _, name := range rules {
+		known := false
+		for _, rule := range LintRules {
+			known = known || rule.Name == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown lint rule %s", name)
+		}
+		enabled[name] = true
+	}
+
+	linter := &linter{instructions: instructions, labels: labelTable(instructions)}
+	for _, rule := range LintRules {
+		if enabled[rule.Name] {
+			linter.rule = rule
+			rule.check(linter)
+		}
+	}
+
+	sortIssues(linter.issues)
+	return linter.issues, nil
+}
+
+func lintUndefinedLabels(linter *linter) {
+	for pc, instruction := range linter.instructions {
+		if label, ok := jumpLabel(instruction); ok {
+			if _, defined := linter.labels[label]; !defined {
+				linter.report(pc, fmt.Sprintf("label %s is not defined", LabelName(label)))
+			}
+		}
+	}
+}
+
+func lintDuplicateLabels(linter *linter) {
+	for pc, instruction := range linter.instructions {
+		if m, ok := instruction.(MarkLabel); ok && linter.labels[m.label] != pc {
+			linter.report(pc, fmt.Sprintf("label %s is already marked at instruction %d", LabelName(m.label), linter.labels[m.label]))
+		}
+	}
+}
+
+func lintUnusedLabels(linter *linter) {
+	used := map[string]bool{}
+	for _, instruction := range linter.instructions {
+		if label, ok := jumpLabel(instruction); ok {
+			used[label] = true
+		}
+	}
+
+	for pc, instruction := range linter.instructions {
+		if m, ok := instruction.(MarkLabel); ok && !used[m.label] && linter.labels[m.label] == pc {
+			linter.report(pc, fmt.Sprintf("label %s is never used", LabelName(m.label)))
+		}
+	}
+}
+
+// lintUnreachableCode reports the first instruction of every run of
+// instructions that can not be reached.
+func lintUnreachableCode(linter *linter) {
+	seen := reachable(linter.instructions, linter.labels)
+	for pc := range linter.instructions {
+		if !seen[pc] && (pc == 0 || seen[pc-1]) {
+			end := pc
+			for end+1 < len(linter.instructions) && !seen[end+1] {
+				end++
+			}
+			linter.report(pc, fmt.Sprintf("instructions %d to %d are unreachable", pc, end))
+		}
+	}
+}
+
+func lintMissingEnd(linter *linter) {
+	last := len(linter.instructions) - 1
+	if last < 0 {
+		return
+	}
+
+	seen := reachable(linter.instructions, linter.labels)
+	if !seen[last] {
+		return
+	}
+
+	switch linter.instructions[last].(type) {
+	case JumpLabel, EndSubroutine, EndProgram:
+	default:
+		linter.report(last, "execution can fall off the end of the program; finish it with end")
+	}
+}
+
+// lintReturnOutsideCall follows the main program without entering any
+// subroutine, so every ret it reaches runs with an empty call stack.
+func lintReturnOutsideCall(linter *linter) {
+	seen := make([]bool, len(linter.instructions))
+	worklist := []int{0}
+	if len(linter.instructions) == 0 {
+		return
+	}
+	seen[0] = true
+
+	for len(worklist) > 0 {
+		pc := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		if _, ok := linter.instructions[pc].(EndSubroutine); ok {
+			linter.report(pc, "ret can be reached outside of any subroutine call")
+		}
+
+		for _, next := range successors(linter.instructions, linter.labels, pc) {
+			if !seen[next] {
+				seen[next] = true
+				worklist = append(worklist, next)
+			}
+		}
+	}
+}
+
+// constant is a stack value that may be known at lint time.
+type constant struct {
+	known bool
+	value int
+}
+
+// constants follows literal values through straight-line code. It calls
+// visit with the values on the stack, top last, before every instruction.
+// Values pushed before the last label are unknown, since the label may be
+// reached from elsewhere.
+func constants(instructions []Instruction, visit func(pc int, stack []constant)) {
+	var stack []constant
+	pop := func() constant {
+		if len(stack) == 0 {
+			return constant{}
+		}
+		value := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return value
+	}
+
+	for pc, instruction := range instructions {
+		if _, ok := instruction.(MarkLabel); ok {
+			stack = nil
+		}
+
+		visit(pc, stack)
+
+		switch i := instruction.(type) {
+		case Push:
+			stack = append(stack, constant{known: true, value: i.value})
+		case Duplicate:
+			value := pop()
+			stack = append(stack, value, value)
+		case Swap:
+			a, b := pop(), pop()
+			stack = append(stack, a, b)
+		case Addition, Subtraction, Multiplication:
+			lhs, rhs := pop(), pop()
+			result := constant{known: lhs.known && rhs.known}
+			switch instruction.(type) {
+			case Addition:
+				result.value = lhs.value + rhs.value
+			case Subtraction:
+				result.value = lhs.value - rhs.value
+			case Multiplication:
+				result.value = lhs.value * rhs.value
+			}
+			stack = append(stack, result)
+		case CallSubroutine, JumpLabel, EndSubroutine, EndProgram:
+			stack = nil
+		default:
+			need, delta := stackEffect(instruction)
+			for n := 0; n < need; n++ {
+				pop()
+			}
+			for n := 0; n < need+delta; n++ {
+				stack = append(stack, constant{})
+			}
+		}
+	}
+}
+
+// peek returns the value n places below the top of stack.
+func peek(stack []constant, n int) constant {
+	if n >= len(stack) {
+		return constant{}
+	}
+	return stack[len(stack)-1-n]
+}
+
+// lintDivisionByZero follows the operand order of Division and Modulo: the
+// top of the stack is divided by the value below it.
+func lintDivisionByZero(linter *linter) {
+	constants(linter.instructions, func(pc int, stack []constant) {
+		switch linter.instructions[pc].(type) {
+		case Division, Modulo:
+			if divisor := peek(stack, 1); divisor.known && divisor.value == 0 {
+				linter.report(pc, fmt.Sprintf("%s divides by zero", Mnemonic(linter.instructions[pc])))
+			}
+		}
+	})
+}
+
+func lintRetrieveNeverStored(linter *linter) {
+	stored := map[int]bool{}
+	anywhere := false
+	retrieved := map[int]int{}
+	var retrieves []int
+
+	constants(linter.instructions, func(pc int, stack []constant) {
+		var address constant
+		switch linter.instructions[pc].(type) {
+		case Store:
+			address = peek(stack, 1)
+		case Getc, Getn:
+			address = peek(stack, 0)
+		case Retrieve:
+			if address := peek(stack, 0); address.known {
+				retrieved[pc] = address.value
+				retrieves = append(retrieves, pc)
+			}
+			return
+		default:
+			return
+		}
+
+		if address.known {
+			stored[address.value] = true
+		} else {
+			anywhere = true
+		}
+	})
+
+	if anywhere {
+		return
+	}
+
+	sort.Ints(retrieves)
+	for _, pc := range retrieves {
+		if !stored[retrieved[pc]] {
+			linter.report(pc, fmt.Sprintf("heap address %d is retrieved but never stored", retrieved[pc]))
+		}
+	}
+}
+
+// lintUninitializedRetrieve reports the retrieves that the value analysis
+// can not prove to read a stored address. Retrieves from a range of
+// addresses are only reported when none of them may have been stored.
+func lintUninitializedRetrieve(linter *linter) {
+	values := linter.valueAnalysis()
+	for pc, instruction := range linter.instructions {
+		if _, ok := instruction.(Retrieve); !ok || !values.Reachable(pc) || values.Initialized(pc) {
+			continue
+		}
+
+		address := values.Operand(pc, 0)
+		switch {
+		case address.isSingleton():
+			linter.report(pc, fmt.Sprintf("heap address %d may be retrieved before it is stored", address.Lo))
+		case !values.MayBeWritten(pc):
+			linter.report(pc, fmt.Sprintf("heap addresses %s are retrieved before any of them is stored", address))
+		}
+	}
+}
+
+// lintInvalidCodePoint reports putc of values outside the Unicode code
+// points or among the surrogates, which are written as U+FFFD. Values
+// without a bound are not reported.
+func lintInvalidCodePoint(linter *linter) {
+	values := linter.valueAnalysis()
+	for pc, instruction := range linter.instructions {
+		if _, ok := instruction.(Putc); !ok || !values.Reachable(pc) {
+			continue
+		}
+
+		value := values.Operand(pc, 0)
+		all, some := validCodePoint(value)
+		switch {
+		case !some:
+			linter.report(pc, fmt.Sprintf("putc writes %s, which is not a Unicode code point", value))
+		case !all && abs(value.Lo) < unbounded && abs(value.Hi) < unbounded:
+			linter.report(pc, fmt.Sprintf("putc may write %s, which includes values that are not Unicode code points", value))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func lintSource(t *testing.T, source string, rules ...string) []Issue {
+	instructions, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("can not assemble %q: %s", source, err.Error())
+	}
+
+	issues, err := Lint(instructions, rules)
+	if err != nil {
+		t.Fatalf("can not lint: %s", err.Error())
+	}
+	return issues
+}
+
+func TestLintCleanProgram(t *testing.T) {
+	issues := lintSource(t, "push 1 call T end label T putn ret", DefaultLintRules()...)
+
+	assert.Empty(t, issues)
+}
+
+func TestLintLabels(t *testing.T) {
+	issues := lintSource(t, "jump S label S label S label T jump TT", "undefined-label", "duplicate-label", "unused-label")
+
+	assert.Equal(t, []Issue{
+		{Rule: "duplicate-label", Severity: SeverityWarning, PC: 2, Message: "label S is already marked at instruction 1"},
+		{Rule: "unused-label", Severity: SeverityWarning, PC: 3, Message: "label T is never used"},
+		{Rule: "undefined-label", Severity: SeverityError, PC: 4, Message: "label TT is not defined"},
+	}, issues)
+}
+
+func TestLintControlFlow(t *testing.T) {
+	issues := lintSource(t, "push 1 jump S push 2 putn label S ret push 3", "unreachable-code", "missing-end", "ret-outside-call")
+
+	assert.Equal(t, []Issue{
+		{Rule: "unreachable-code", Severity: SeverityWarning, PC: 2, Message: "instructions 2 to 3 are unreachable"},
+		{Rule: "ret-outside-call", Severity: SeverityError, PC: 5, Message: "ret can be reached outside of any subroutine call"},
+		{Rule: "unreachable-code", Severity: SeverityWarning, PC: 6, Message: "instructions 6 to 6 are unreachable"},
+	}, issues)
+
+	issues = lintSource(t, "push 0 jz S end label S push 1", "missing-end")
+
+	assert.Equal(t, []Issue{
+		{Rule: "missing-end", Severity: SeverityWarning, PC: 4, Message: "execution can fall off the end of the program; finish it with end"},
+	}, issues)
+}
+
+func TestLintDivisionByZero(t *testing.T) {
+	issues := lintSource(t, "push 0 push 4 div push 2 push 0 mod push 0 dup swap mod end", "division-by-zero")
+
+	assert.Equal(t, []Issue{
+		{Rule: "division-by-zero", Severity: SeverityError, PC: 2, Message: "div divides by zero"},
+		{Rule: "division-by-zero", Severity: SeverityError, PC: 9, Message: "mod divides by zero"},
+	}, issues)
+
+	// The subroutine may change the stack, so its values are not known after
+	// the call.
+	issues = lintSource(t, "push 0 push 1 call S div end label S swap ret", "division-by-zero")
+	assert.Empty(t, issues)
+}
+
+func TestLintRetrieveNeverStored(t *testing.T) {
+	issues := lintSource(t, "push 1 push 5 store push 1 retrieve push 2 retrieve end", "retrieve-never-stored")
+
+	assert.Equal(t, []Issue{
+		{Rule: "retrieve-never-stored", Severity: SeverityWarning, PC: 6, Message: "heap address 2 is retrieved but never stored"},
+	}, issues)
+
+	issues = lintSource(t, "getn push 2 retrieve end", "retrieve-never-stored")
+	assert.Empty(t, issues)
+
+	issues = lintSource(t, "push 2 push 2 store push 1 call S retrieve end label S discard push 2 ret", "retrieve-never-stored")
+	assert.Empty(t, issues)
+}
+
+func TestLintRecursiveProgram(t *testing.T) {
+	issues := lintSource(t, `
+		push 3 call S end
+		label S dup jz T push -1 putc push 7 retrieve putn push 1 swap sub call S ret
+		label T discard ret`, "uninitialized-retrieve", "invalid-code-point")
+
+	assert.Equal(t, []Issue{
+		{Rule: "invalid-code-point", Severity: SeverityWarning, PC: 7, Message: "putc writes -1, which is not a Unicode code point"},
+		{Rule: "uninitialized-retrieve", Severity: SeverityWarning, PC: 9, Message: "heap address 7 may be retrieved before it is stored"},
+	}, issues)
+}
+
+func TestLintUnknownRule(t *testing.T) {
+	_, err := Lint(nil, []string{"no-such-rule"})
+
+	assert.NotNil(t, err)
+}
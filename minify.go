@@ -0,0 +1,146 @@
+package whitespace_go
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encode renders instructions as Whitespace without any comment. Numbers
+// are written with the fewest binary digits.
+func Encode(instructions []Instruction) string {
+	var builder strings.Builder
+	for _, instruction := range instructions {
+		command := commandOf(instruction)
+		builder.WriteString(command.code)
+		switch command.parameter {
+		case numberParameter:
+			builder.WriteString(encodeNumber(instruction.(Push).value))
+		case labelParameter:
+			builder.WriteString(instructionLabel(instruction) + LF)
+		}
+	}
+	return builder.String()
+}
+
+// commandOf returns the command that encodes instruction.
+func commandOf(instruction Instruction) command {
+	name := strings.Fields(Mnemonic(instruction))[0]
+	for _, command := range commands {
+		if command.name == name {
+			return command
+		}
+	}
+	panic("no command for " + name)
+}
+
+func encodeNumber(n int) string {
+	sign, magnitude := SPACE, uint64(n)
+	if n < 0 {
+		sign, magnitude = TAB, -magnitude
+	}
+	digits := strconv.FormatUint(magnitude, 2)
+	return sign + strings.NewReplacer("0", SPACE, "1", TAB).Replace(digits) + LF
+}
+
+// instructionLabel returns the label an instruction marks or jumps to.
+func instructionLabel(instruction Instruction) string {
+	if m, ok := instruction.(MarkLabel); ok {
+		return m.label
+	}
+	label, _ := jumpLabel(instruction)
+	return label
+}
+
+// withLabel returns instruction with its label replaced.
+func withLabel(instruction Instruction, label string) Instruction {
+	switch instruction.(type) {
+	case MarkLabel:
+		return MarkLabel{label: label}
+	case CallSubroutine:
+		return CallSubroutine{label: label}
+	case JumpLabel:
+		return JumpLabel{label: label}
+	case JumpLabelWhenZero:
+		return JumpLabelWhenZero{label: label}
+	case JumpLabelWhenNegative:
+		return JumpLabelWhenNegative{label: label}
+	default:
+		return instruction
+	}
+}
+
+// shortLabel returns the nth label in order of length, S before T.
+func shortLabel(n int) string {
+	length := 1
+	for n >= 1<<length {
+		n -= 1 << length
+		length++
+	}
+
+	var builder strings.Builder
+	for bit := length - 1; bit >= 0; bit-- {
+		if n&(1<<bit) == 0 {
+			builder.WriteString(SPACE)
+		} else {
+			builder.WriteString(TAB)
+		}
+	}
+	return builder.String()
+}
+
+// Minify returns the shortest encoding of instructions this package knows.
+// MarkLabels nothing jumps to are dropped, as are later definitions of a
+// label, which jumps never reach. Labels are renamed so the most used get
+// the shortest names. The result is parsed back to make sure it is the same
+// program.
+func Minify(instructions []Instruction) (string, error) {
+	labels := labelTable(instructions)
+	used := map[string]bool{}
+	for _, instruction := range instructions {
+		if label, ok := jumpLabel(instruction); ok {
+			used[label] = true
+		}
+	}
+
+	var kept []Instruction
+	for pc, instruction := range instructions {
+		if m, ok := instruction.(MarkLabel); ok && (!used[m.label] || labels[m.label] != pc) {
+			continue
+		}
+		kept = append(kept, instruction)
+	}
+
+	var names []string
+	uses := map[string]int{}
+	for _, instruction := range kept {
+		if label := instructionLabel(instruction); label != "" {
+			if uses[label] == 0 {
+				names = append(names, label)
+			}
+			uses[label]++
+		}
+	}
+	sort.SliceStable(names, func(a, b int) bool {
+		return uses[names[a]] > uses[names[b]]
+	})
+
+	renamed := map[string]string{}
+	for n, name := range names {
+		renamed[name] = shortLabel(n)
+	}
+
+	var minified []Instruction
+	for _, instruction := range kept {
+		minified = append(minified, withLabel(instruction, renamed[instructionLabel(instruction)]))
+	}
+
+	encoded := Encode(minified)
+	parser := NewParser("(minified)", encoded)
+	if err := parser.ParseAll(); err != nil || !reflect.DeepEqual(parser.Instructions, minified) {
+		return "", errors.New("minified program does not parse to the same instructions")
+	}
+	return encoded, nil
+}
@@ -0,0 +1,71 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeNumbers(t *testing.T) {
+	assert.Equal(t, SPACE+SPACE+SPACE+SPACE+LF, Encode([]Instruction{Push{value: 0}}))
+	assert.Equal(t, SPACE+SPACE+SPACE+TAB+SPACE+TAB+LF, Encode([]Instruction{Push{value: 5}}))
+	assert.Equal(t, SPACE+SPACE+TAB+TAB+SPACE+LF, Encode([]Instruction{Push{value: -2}}))
+}
+
+func TestShortLabel(t *testing.T) {
+	var labels []string
+	for n := 0; n < 7; n++ {
+		labels = append(labels, LabelName(shortLabel(n)))
+	}
+	assert.Equal(t, []string{"S", "T", "SS", "ST", "TS", "TT", "SSS"}, labels)
+}
+
+func TestMinify(t *testing.T) {
+	source := "push" + SPACE + SPACE + SPACE + SPACE + SPACE + TAB + LF +
+		LF + SPACE + SPACE + SPACE + SPACE + SPACE + LF + "unused" +
+		LF + SPACE + SPACE + TAB + TAB + TAB + LF + "loop" +
+		SPACE + LF + SPACE +
+		LF + TAB + SPACE + TAB + TAB + TAB + LF +
+		LF + SPACE + LF + TAB + TAB + TAB + LF +
+		LF + SPACE + SPACE + TAB + TAB + TAB + LF + "again" +
+		LF + LF + LF
+	parser := NewParser("minify.ws", source)
+	assert.Nil(t, parser.ParseAll())
+
+	minified, err := Minify(parser.Instructions)
+	assert.Nil(t, err)
+
+	expected := SPACE + SPACE + SPACE + TAB + LF +
+		LF + SPACE + SPACE + SPACE + LF +
+		SPACE + LF + SPACE +
+		LF + TAB + SPACE + SPACE + LF +
+		LF + SPACE + LF + SPACE + LF +
+		LF + LF + LF
+	assert.Equal(t, expected, minified)
+	assert.True(t, len(minified) < len(source))
+}
+
+func TestMinifyGivesShortestLabelsToMostUsed(t *testing.T) {
+	rare, common := TAB+TAB+SPACE, SPACE+SPACE+TAB
+	instructions := []Instruction{
+		CallSubroutine{label: rare},
+		JumpLabel{label: common},
+		MarkLabel{label: rare},
+		JumpLabelWhenZero{label: common},
+		MarkLabel{label: common},
+		JumpLabelWhenNegative{label: common},
+	}
+
+	minified, err := Minify(instructions)
+	assert.Nil(t, err)
+
+	parser := NewParser("minify.ws", minified)
+	assert.Nil(t, parser.ParseAll())
+	assert.Equal(t, []Instruction{
+		CallSubroutine{label: TAB},
+		JumpLabel{label: SPACE},
+		MarkLabel{label: TAB},
+		JumpLabelWhenZero{label: SPACE},
+		MarkLabel{label: SPACE},
+		JumpLabelWhenNegative{label: SPACE},
+	}, parser.Instructions)
+}
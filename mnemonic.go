@@ -0,0 +1,84 @@
+package whitespace_go
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Mnemonic returns a human readable assembly form of an instruction such as
+// "push 72" or "jz TS".
+func Mnemonic(instruction Instruction) string {
+	switch i := instruction.(type) {
+	case Push:
+		return fmt.Sprintf("push %d", i.value)
+	case Duplicate:
+		return "dup"
+	case Swap:
+		return "swap"
+	case Discard:
+		return "discard"
+	case Addition:
+		return "add"
+	case Subtraction:
+		return "sub"
+	case Multiplication:
+		return "mul"
+	case Division:
+		return "div"
+	case Modulo:
+		return "mod"
+	case Store:
+		return "store"
+	case Retrieve:
+		return "retrieve"
+	case Putc:
+		return "putc"
+	case Putn:
+		return "putn"
+	case Getc:
+		return "getc"
+	case Getn:
+		return "getn"
+	case MarkLabel:
+		return "label " + LabelName(i.label)
+	case CallSubroutine:
+		return "call " + LabelName(i.label)
+	case JumpLabel:
+		return "jump " + LabelName(i.label)
+	case JumpLabelWhenZero:
+		return "jz " + LabelName(i.label)
+	case JumpLabelWhenNegative:
+		return "jn " + LabelName(i.label)
+	case EndSubroutine:
+		return "ret"
+	case EndProgram:
+		return "end"
+	default:
+		return fmt.Sprintf("%T", instruction)
+	}
+}
+
+// LabelName renders a label made of spaces and tabs with the visible letters
+// S and T.
+func LabelName(label string) string {
+	return strings.NewReplacer(SPACE, "S", TAB, "T").Replace(label)
+}
+
+// Disassemble renders instructions one mnemonic per line.
+func Disassemble(instructions []Instruction) string {
+	var builder strings.Builder
+	for _, instruction := range instructions {
+		builder.WriteString(Mnemonic(instruction))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// programHash identifies a program by the SHA-256 of its disassembly so data
+// recorded against one program is not applied to another.
+func programHash(instructions []Instruction) string {
+	sum := sha256.Sum256([]byte(Disassemble(instructions)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,151 @@
+package whitespace_go
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Notation is a way to write the three tokens. Everything in a source that
+// is not one of its symbols is a comment.
+type Notation struct {
+	Space string
+	Tab   string
+	LF    string
+}
+
+var (
+	// WhitespaceNotation is the language itself.
+	WhitespaceNotation = Notation{Space: SPACE, Tab: TAB, LF: LF}
+	// STLNotation writes the tokens as the letters S, T and L.
+	STLNotation = Notation{Space: "S", Tab: "T", LF: "L"}
+	// BracketNotation writes the tokens as [Space], [Tab] and [LF].
+	BracketNotation = Notation{Space: "[Space]", Tab: "[Tab]", LF: "[LF]"}
+)
+
+var notations = map[string]Notation{
+	"whitespace": WhitespaceNotation,
+	"stl":        STLNotation,
+	"bracket":    BracketNotation,
+}
+
+// notationExtensions selects the notation of a file from its extension.
+var notationExtensions = map[string]Notation{
+	".stl": STLNotation,
+	".wsb": BracketNotation,
+}
+
+// ParseNotation returns the notation spec names, whitespace, stl or
+// bracket, or the one spec gives as its space, tab and LF symbols separated
+// by commas, such as "🌑,🌓,🌕".
+func ParseNotation(spec string) (Notation, error) {
+	if notation, ok := notations[strings.ToLower(spec)]; ok {
+		return notation, nil
+	}
+
+	symbols := strings.Split(spec, ",")
+	if len(symbols) != 3 {
+		return Notation{}, errors.New("notation must be whitespace, stl, bracket or three symbols separated by commas")
+	}
+	notation := Notation{Space: symbols[0], Tab: symbols[1], LF: symbols[2]}
+	if err := notation.validate(); err != nil {
+		return Notation{}, err
+	}
+	return notation, nil
+}
+
+// NotationForFile returns the notation the extension of filename stands for:
+// .stl for STLNotation, .wsb for BracketNotation and WhitespaceNotation for
+// any other.
+func NotationForFile(filename string) Notation {
+	if notation, ok := notationExtensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return notation
+	}
+	return WhitespaceNotation
+}
+
+// validate makes sure every source has a single reading: the symbols are
+// not empty and none of them starts another.
+func (notation Notation) validate() error {
+	symbols := notation.symbols()
+	for n, symbol := range symbols {
+		if symbol == "" {
+			return errors.New("notation symbols must not be empty")
+		}
+		for m, other := range symbols {
+			if n != m && strings.HasPrefix(other, symbol) {
+				return errors.New("notation symbol " + symbol + " starts " + other)
+			}
+		}
+	}
+	return nil
+}
+
+func (notation Notation) symbols() []string {
+	return []string{notation.Space, notation.Tab, notation.LF}
+}
+
+func (notation Notation) isZero() bool {
+	return notation == Notation{}
+}
+
+// match returns the token whose symbol starts source at index and the
+// number of runes of the symbol, or 0 when no symbol starts there.
+func (notation Notation) match(source []rune, index int) (rune, int) {
+	for n, symbol := range notation.symbols() {
+		if length := runesPrefix(source[index:], symbol); length > 0 {
+			return symbolTokens[n], length
+		}
+	}
+	return 0, 0
+}
+
+// symbolTokens are the tokens in the order of symbols.
+var symbolTokens = []rune(SPACE + TAB + LF)
+
+func runesPrefix(source []rune, symbol string) int {
+	length := 0
+	for _, r := range symbol {
+		if length >= len(source) || source[length] != r {
+			return 0
+		}
+		length++
+	}
+	return length
+}
+
+// Decode returns the tokens of source, written in notation, as Whitespace.
+func (notation Notation) Decode(source string) string {
+	runes := []rune(source)
+	var builder strings.Builder
+	for index := 0; index < len(runes); index++ {
+		if token, length := notation.match(runes, index); length > 0 {
+			builder.WriteRune(token)
+			index += length - 1
+		}
+	}
+	return builder.String()
+}
+
+// Encode writes the tokens of a Whitespace source in notation and drops its
+// comments, which could contain symbols of notation. When line breaks are
+// comments in notation, every LF is followed by one so the result is
+// readable.
+func (notation Notation) Encode(source string) string {
+	readable := notation != WhitespaceNotation && !strings.Contains(strings.Join(notation.symbols(), ""), LF)
+	var builder strings.Builder
+	for _, r := range source {
+		switch string(r) {
+		case SPACE:
+			builder.WriteString(notation.Space)
+		case TAB:
+			builder.WriteString(notation.Tab)
+		case LF:
+			builder.WriteString(notation.LF)
+			if readable {
+				builder.WriteString(LF)
+			}
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,57 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSTLNotation(t *testing.T) {
+	parser := NewParser("hello.stl", "push 1: SSSTL\nputn:  TLST\nend: LLL\n")
+	parser.Options.Notation = STLNotation
+	assert.Nil(t, parser.ParseAll())
+
+	assert.Equal(t, []Instruction{Push{value: 1}, Putn{}, EndProgram{}}, parser.Instructions)
+	assert.Equal(t, []Position{
+		{Offset: 8, End: 13, Line: 1, Column: 9},
+		{Offset: 21, End: 25, Line: 2, Column: 8},
+		{Offset: 31, End: 34, Line: 3, Column: 6},
+	}, parser.Positions)
+}
+
+func TestParseBracketNotationReportsErrorsAtSymbols(t *testing.T) {
+	parser := NewParser("bad.wsb", "[Space][LF][Space]\n[Tab][Tab][LF]")
+	parser.Options.Notation = BracketNotation
+	err := parser.ParseAll()
+
+	assert.Equal(t, "Parse error: expected heap access command at bad.wsb:2:11", err.Error())
+	assert.Equal(t, []Instruction{Duplicate{}}, parser.Instructions)
+}
+
+func TestParseNotation(t *testing.T) {
+	notation, err := ParseNotation("STL")
+	assert.Nil(t, err)
+	assert.Equal(t, STLNotation, notation)
+
+	notation, err = ParseNotation("🌑,🌓,🌕")
+	assert.Nil(t, err)
+	assert.Equal(t, Notation{Space: "🌑", Tab: "🌓", LF: "🌕"}, notation)
+
+	_, err = ParseNotation("a,ab,c")
+	assert.Equal(t, "notation symbol a starts ab", err.Error())
+	_, err = ParseNotation("a,b")
+	assert.NotNil(t, err)
+}
+
+func TestNotationForFile(t *testing.T) {
+	assert.Equal(t, STLNotation, NotationForFile("dir/hello.STL"))
+	assert.Equal(t, BracketNotation, NotationForFile("hello.wsb"))
+	assert.Equal(t, WhitespaceNotation, NotationForFile("hello.ws"))
+}
+
+func TestConvertNotations(t *testing.T) {
+	source := "push" + SPACE + SPACE + TAB + TAB + LF + "dup" + SPACE + LF + SPACE
+	stl := STLNotation.Encode(source)
+	assert.Equal(t, "SSTTL\nSL\nS", stl)
+	assert.Equal(t, "[Space][Space][Tab][Tab][LF]\n[Space][LF]\n[Space]", BracketNotation.Encode(STLNotation.Decode(stl)))
+	assert.Equal(t, SPACE+SPACE+TAB+TAB+LF+SPACE+LF+SPACE, STLNotation.Decode(stl))
+}
@@ -1,7 +1,10 @@
 package whitespace_go
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -14,15 +17,68 @@ var (
 	Tokens = []string{TAB, LF, SPACE}
 )
 
+// Position is where an instruction was parsed from. Offset and End are the
+// byte offsets of its first token and just past its last one. Line and
+// Column locate its first token; both count from 1 and Column counts runes.
+type Position struct {
+	Offset int
+	End    int
+	Line   int
+	Column int
+}
+
+func (position Position) String() string {
+	return fmt.Sprintf("%d:%d", position.Line, position.Column)
+}
+
+// positionAt returns the position of the instruction at pc. Programs loaded
+// without a source map have none.
+func positionAt(positions []Position, pc int) (Position, bool) {
+	if pc < 0 || pc >= len(positions) {
+		return Position{}, false
+	}
+	return positions[pc], true
+}
+
+// ParseOptions controls how the characters other than the three tokens are
+// read. With the zero value they are all comments.
+type ParseOptions struct {
+	// Strict reports characters that look like whitespace but are not
+	// tokens, such as no-break spaces or a CR, as parse errors.
+	Strict bool
+	// CRLF reads CR LF as a single LF token.
+	CRLF bool
+	// Notation is how the tokens are written. The zero value stands for
+	// WhitespaceNotation.
+	Notation Notation
+}
+
+func (options ParseOptions) notation() Notation {
+	if options.Notation.isZero() {
+		return WhitespaceNotation
+	}
+	return options.Notation
+}
+
+// Parser turns source code into Instructions. Positions[pc] is where
+// Instructions[pc] was parsed from. A parse error does not stop the parser:
+// it skips the offending token and goes on with the next instruction, so
+// ParseAll reports every error of the source as ParseErrors.
 type Parser struct {
 	filename      string
 	rawSourceCode string
 	sourceCode    []rune
+	offsets       []int
+	lineStarts    []int
 	currentIndex  int
-	currentLine   int
-	currentColumn int
-	newLine       bool
+	tokenStart    int
+	token         rune
+	start         Position
+	command       []rune
+	suspicious    ParseErrors
+	Options       ParseOptions
 	Instructions  []Instruction
+	Positions     []Position
 }
 
 func NewParser(filename string, rawSourceCode string) Parser {
@@ -34,19 +90,45 @@ func NewParser(filename string, rawSourceCode string) Parser {
 
 func (parser *Parser) ParseAll() error {
 	parser.sourceCode = []rune(parser.rawSourceCode)
+	parser.offsets = runeOffsets(parser.rawSourceCode)
+	parser.lineStarts = nil
 	parser.currentIndex = -1
-	parser.currentLine = 1
-	parser.currentColumn = 0
-	parser.newLine = false
+	parser.Instructions = nil
+	parser.Positions = nil
+	parser.suspicious = nil
+
+	var errs ParseErrors
+	for {
+		_, err := parser.parse()
+		if err == nil {
+			break
+		}
 
-	instructions, err := parser.parse()
-	parser.Instructions = instructions
+		errs = append(errs, err.(*Diagnostic))
+		if parser.currentIndex >= len(parser.sourceCode) || len(errs) >= maxDiagnostics {
+			break
+		}
+	}
+
+	errs = append(errs, parser.suspicious...)
+	sort.SliceStable(errs, func(a, b int) bool {
+		return errs[a].Position.Offset < errs[b].Position.Offset
+	})
+	if len(errs) > maxDiagnostics {
+		errs = errs[:maxDiagnostics]
+	}
 
-	return err
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
 func (parser *Parser) parse() ([]Instruction, error) {
+	parser.command = parser.command[:0]
 	token := parser.nextToken()
+	parser.markStart(token)
+
 	switch string(token) {
 	case SPACE:
 		return parser.parseStackManipulation()
@@ -284,42 +366,134 @@ func (parser *Parser) parseLabel() (string, error) {
 	return label, nil
 }
 
-func (parser *Parser) addInstruction(instruction Instruction) ([]Instruction, error) {
-	parser.Instructions = append(parser.Instructions, instruction)
-	return parser.parse()
+// runeOffsets returns the byte offset of every rune of source, in the same
+// way []rune(source) splits it, followed by the length of source.
+func runeOffsets(source string) []int {
+	offsets := make([]int, 0, len(source)+1)
+	for offset := range source {
+		offsets = append(offsets, offset)
+	}
+	return append(offsets, len(source))
 }
 
-func (parser *Parser) nextToken() rune {
-	parser.currentIndex++
+func (parser *Parser) byteOffset(index int) int {
+	if parser.offsets == nil {
+		parser.offsets = runeOffsets(string(parser.sourceCode))
+	}
+	return parser.offsets[index]
+}
 
-	if parser.newLine {
-		parser.newLine = false
-		parser.currentColumn = 0
+// markStart records the position of token, the first token of the next
+// instruction.
+func (parser *Parser) markStart(token rune) {
+	if parser.currentIndex >= len(parser.sourceCode) {
+		return
 	}
 
-	parser.currentColumn++
+	parser.start, _ = parser.currentPosition()
+}
 
+// currentPosition returns the position of the current token, or of the end
+// of the source once it is exhausted, and the source line it is on.
+func (parser *Parser) currentPosition() (Position, string) {
 	if parser.currentIndex >= len(parser.sourceCode) {
-		return 0
+		return parser.positionAt(len(parser.sourceCode))
 	}
+	return parser.positionAt(parser.tokenStart)
+}
 
-	for !contains(parser.currentToken()) {
-		parser.currentIndex++
-		if parser.currentIndex >= len(parser.sourceCode) {
-			return 0
+// positionAt returns the position of the rune at index, or of the end of the
+// source, and the source line it is on.
+func (parser *Parser) positionAt(index int) (Position, string) {
+	if parser.lineStarts == nil {
+		parser.lineStarts = []int{0}
+		for n, r := range parser.sourceCode {
+			if string(r) == LF {
+				parser.lineStarts = append(parser.lineStarts, n+1)
+			}
 		}
 	}
 
-	if string(parser.currentToken()) == LF {
-		parser.currentLine++
-		parser.newLine = true
+	if parser.Options.CRLF && index > 0 && index < len(parser.sourceCode) &&
+		string(parser.sourceCode[index]) == LF && parser.sourceCode[index-1] == '\r' {
+		index--
+	}
+
+	line := sort.Search(len(parser.lineStarts), func(n int) bool {
+		return parser.lineStarts[n] > index
+	})
+	lineStart, lineEnd := parser.lineStarts[line-1], len(parser.sourceCode)
+	if line < len(parser.lineStarts) {
+		lineEnd = parser.lineStarts[line]
+	}
+	source := string(parser.sourceCode[lineStart:lineEnd])
+	if parser.Options.CRLF {
+		source = strings.Replace(source, "\r\n", LF, 1)
+	}
+
+	position := Position{
+		Offset: parser.byteOffset(index),
+		Line:   line,
+		Column: index - lineStart + 1,
+	}
+	return position, source
+}
+
+// checkCharacter reports the comment character under the cursor in strict
+// mode when it looks like whitespace that was meant to be a token. Only
+// Whitespace itself is checked; in other notations whitespace is layout.
+func (parser *Parser) checkCharacter() {
+	if !parser.Options.Strict || parser.Options.notation() != WhitespaceNotation {
+		return
+	}
+
+	r := parser.sourceCode[parser.currentIndex]
+	name, ok := suspiciousCharacters[r]
+	switch {
+	case r == '\r' && parser.currentIndex+1 < len(parser.sourceCode) && string(parser.sourceCode[parser.currentIndex+1]) == LF:
+		if parser.Options.CRLF {
+			return
+		}
+		name = "carriage return before LF"
+	case r == '\uFEFF' && parser.currentIndex == 0:
+		return
+	case !ok && unicode.IsSpace(r):
+		name = "space"
+	case !ok:
+		return
 	}
 
-	return parser.currentToken()
+	parser.suspicious = append(parser.suspicious, characterError(parser, r, name))
+}
+
+func (parser *Parser) addInstruction(instruction Instruction) ([]Instruction, error) {
+	position := parser.start
+	position.End = parser.byteOffset(parser.currentIndex + 1)
+
+	parser.Instructions = append(parser.Instructions, instruction)
+	parser.Positions = append(parser.Positions, position)
+	return parser.parse()
+}
+
+// nextToken reads the symbol of the next token, leaving currentIndex on its
+// last rune and tokenStart on its first.
+func (parser *Parser) nextToken() rune {
+	notation := parser.Options.notation()
+	for parser.currentIndex++; parser.currentIndex < len(parser.sourceCode); parser.currentIndex++ {
+		if token, length := notation.match(parser.sourceCode, parser.currentIndex); length > 0 {
+			parser.tokenStart = parser.currentIndex
+			parser.currentIndex += length - 1
+			parser.token = token
+			parser.command = append(parser.command, token)
+			return token
+		}
+		parser.checkCharacter()
+	}
+	return 0
 }
 
 func (parser *Parser) currentToken() rune {
-	return parser.sourceCode[parser.currentIndex]
+	return parser.token
 }
 
 func contains(r rune) bool {
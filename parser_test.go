@@ -2,6 +2,7 @@ package whitespace_go
 
 import (
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -183,3 +184,108 @@ func TestParseHeapAccessInstruction(t *testing.T) {
 		assert.Equal(t, instructions[0], expected)
 	}
 }
+
+func TestParsePositions(t *testing.T) {
+	parser := NewParser("positions.ws", "é"+SPACE+SPACE+SPACE+TAB+LF+"x"+TAB+LF+SPACE+TAB+LF+LF+LF)
+	err := parser.ParseAll()
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Position{
+		{Offset: 2, End: 7, Line: 1, Column: 2},
+		{Offset: 8, End: 12, Line: 2, Column: 2},
+		{Offset: 12, End: 15, Line: 3, Column: 3},
+	}, parser.Positions)
+	assert.Equal(t, "3:3", parser.Positions[2].String())
+}
+
+func TestParseReportsEveryError(t *testing.T) {
+	source := SPACE + SPACE + LF +
+		TAB + TAB + LF +
+		SPACE + LF + SPACE +
+		LF + SPACE + SPACE
+	parser := NewParser("errors.ws", source)
+	err := parser.ParseAll()
+
+	errs, ok := err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(errs))
+
+	assert.Equal(t, "Parse error: expected sign at errors.ws:1:3", errs[0].Error())
+	assert.Equal(t, "LF", errs[0].Found)
+	assert.Equal(t, "Space (positive) or Tab (negative)", errs[0].Expected)
+
+	assert.Equal(t, "Parse error: expected heap access command at errors.ws:2:3", errs[1].Error())
+	assert.Equal(t, "Space (store) or Tab (retrieve)", errs[1].Expected)
+
+	assert.Equal(t, Position{Offset: 12, Line: 5, Column: 3}, errs[2].Position)
+	assert.Equal(t, "end of file", errs[2].Found)
+	assert.Equal(t, "Space or Tab", errs[2].Expected)
+
+	assert.Equal(t, []Instruction{Duplicate{}}, parser.Instructions)
+	assert.Equal(t, errs[0].Error()+"\n"+errs[1].Error()+"\n"+errs[2].Error(), err.Error())
+}
+
+func TestRenderDiagnostic(t *testing.T) {
+	parser := NewParser("render.ws", "add"+TAB+SPACE+TAB+LF)
+	err := parser.ParseAll()
+
+	expected := "Parse error: expected artithemetic command at render.ws:1:7\n" +
+		" 1 | add→·→¶\n" +
+		"   |       ^\n" +
+		"   = found LF, expected Space (div) or Tab (mod)"
+	assert.Equal(t, expected, RenderError(err))
+}
+
+func TestExpectedTokens(t *testing.T) {
+	assert.Equal(t, "Space (push) or LF (dup, swap, discard)", expectedTokens(SPACE))
+	assert.Equal(t, "Space (add, sub, mul, div, mod), Tab (store, retrieve) or LF (putc, putn, getc, getn)", expectedTokens(TAB))
+	assert.Equal(t, "Space (0), Tab (1) or LF (end of number)", expectedTokens(SPACE+SPACE+TAB+SPACE))
+	assert.Equal(t, "Space, Tab or LF (end of label)", expectedTokens(LF+SPACE+TAB+TAB))
+}
+
+func TestLenientParsingSkipsSuspiciousCharacters(t *testing.T) {
+	parser := NewParser("lenient.ws", SPACE+LF+" "+SPACE+"\r"+LF+LF+LF)
+	assert.Nil(t, parser.ParseAll())
+	assert.Equal(t, []Instruction{Duplicate{}, EndProgram{}}, parser.Instructions)
+}
+
+func TestStrictParsingReportsSuspiciousCharacters(t *testing.T) {
+	source := SPACE + LF + " " + SPACE + "\r" + LF + "\r\v" + LF + LF + "　"
+	parser := NewParser("strict.ws", source)
+	parser.Options = ParseOptions{Strict: true}
+	err := parser.ParseAll()
+
+	errs, ok := err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		"Parse error: suspicious character U+00A0 (no-break space) at strict.ws:2:1",
+		"Parse error: suspicious character U+000D (carriage return before LF) at strict.ws:2:3",
+		"Parse error: suspicious character U+000D (carriage return) at strict.ws:3:1",
+		"Parse error: suspicious character U+000B (vertical tab) at strict.ws:3:2",
+		"Parse error: suspicious character U+3000 (ideographic space) at strict.ws:5:1",
+	}, strings.Split(err.Error(), "\n"))
+	assert.Equal(t, "U+000D", errs[2].Found)
+	assert.Equal(t, []Instruction{Duplicate{}, EndProgram{}}, parser.Instructions)
+}
+
+func TestCRLFParsing(t *testing.T) {
+	source := SPACE + LF + SPACE + "\r\n" + LF + LF + SPACE + TAB
+	parser := NewParser("crlf.ws", source)
+	parser.Options = ParseOptions{Strict: true, CRLF: true}
+	err := parser.ParseAll()
+
+	assert.Equal(t, "Parse error: expected stack manipulation command at crlf.ws:5:2", err.Error())
+	assert.Equal(t, []Instruction{Duplicate{}, EndProgram{}}, parser.Instructions)
+	assert.Equal(t, []Position{
+		{Offset: 0, End: 3, Line: 1, Column: 1},
+		{Offset: 3, End: 7, Line: 2, Column: 2},
+	}, parser.Positions)
+
+	parser = NewParser("crlf.ws", SPACE+TAB+"\r\n"+LF+LF)
+	parser.Options = ParseOptions{CRLF: true}
+	err = parser.ParseAll()
+	assert.Equal(t, "Parse error: expected stack manipulation command at crlf.ws:1:2\n"+
+		" 1 | ·→¶\n"+
+		"   |  ^\n"+
+		"   = found Tab, expected Space (push) or LF (dup, swap, discard)", RenderError(err))
+}
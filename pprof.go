@@ -0,0 +1,178 @@
+package whitespace_go
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// Field numbers of the profile.proto messages understood by go tool pprof.
+const (
+	pprofProfileSampleType  = 1
+	pprofProfileSample      = 2
+	pprofProfileLocation    = 4
+	pprofProfileFunction    = 5
+	pprofProfileStringTable = 6
+	pprofProfilePeriodType  = 11
+	pprofProfilePeriod      = 12
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationID = 1
+	pprofSampleValue      = 2
+
+	pprofLocationID   = 1
+	pprofLocationLine = 4
+
+	pprofLineFunctionID = 1
+	pprofLineLine       = 2
+
+	pprofFunctionID         = 1
+	pprofFunctionName       = 2
+	pprofFunctionSystemName = 3
+	pprofFunctionFilename   = 4
+)
+
+type protoBuffer struct {
+	bytes []byte
+}
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		b.bytes = append(b.bytes, byte(v)|0x80)
+		v >>= 7
+	}
+	b.bytes = append(b.bytes, byte(v))
+}
+
+func (b *protoBuffer) uint64(field int, v uint64) {
+	b.varint(uint64(field)<<3 | 0)
+	b.varint(v)
+}
+
+func (b *protoBuffer) int64(field int, v int64) {
+	b.uint64(field, uint64(v))
+}
+
+func (b *protoBuffer) packed(field int, values []uint64) {
+	var packed protoBuffer
+	for _, v := range values {
+		packed.varint(v)
+	}
+	b.embedded(field, packed.bytes)
+}
+
+func (b *protoBuffer) embedded(field int, v []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(v)))
+	b.bytes = append(b.bytes, v...)
+}
+
+type pprofStrings struct {
+	table []string
+	index map[string]int64
+}
+
+func (s *pprofStrings) id(str string) int64 {
+	if s.index == nil {
+		s.index = map[string]int64{}
+	}
+	if id, ok := s.index[str]; ok {
+		return id
+	}
+	id := int64(len(s.table))
+	s.table = append(s.table, str)
+	s.index[str] = id
+	return id
+}
+
+// WritePprof writes the collected samples as a gzipped profile.proto so the
+// program can be inspected with go tool pprof or any flame graph tool that
+// reads pprof profiles. Every subroutine becomes a function and every
+// instruction a location whose line is its 1-based instruction number.
+func (profiler *Profiler) WritePprof(w io.Writer) error {
+	var table pprofStrings
+	table.id("")
+
+	var profile protoBuffer
+
+	var valueType protoBuffer
+	valueType.int64(pprofValueTypeType, table.id("instructions"))
+	valueType.int64(pprofValueTypeUnit, table.id("count"))
+	profile.embedded(pprofProfileSampleType, valueType.bytes)
+
+	functionIDs := map[string]uint64{}
+	locationIDs := map[profileFrame]uint64{}
+	var functions, locations [][]byte
+
+	keys := make([]string, 0, len(profiler.samples))
+	for key := range profiler.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		sample := profiler.samples[key]
+		var ids []uint64
+		for _, frame := range profiler.frames(sample.stack) {
+			functionID, ok := functionIDs[frame.function]
+			if !ok {
+				functionID = uint64(len(functionIDs) + 1)
+				functionIDs[frame.function] = functionID
+
+				var function protoBuffer
+				function.uint64(pprofFunctionID, functionID)
+				function.int64(pprofFunctionName, table.id(frame.function))
+				function.int64(pprofFunctionSystemName, table.id(frame.function))
+				function.int64(pprofFunctionFilename, table.id(profiler.filename))
+				functions = append(functions, function.bytes)
+			}
+
+			locationID, ok := locationIDs[frame]
+			if !ok {
+				locationID = uint64(len(locationIDs) + 1)
+				locationIDs[frame] = locationID
+
+				var line protoBuffer
+				line.uint64(pprofLineFunctionID, functionID)
+				line.int64(pprofLineLine, int64(frame.pc+1))
+
+				var location protoBuffer
+				location.uint64(pprofLocationID, locationID)
+				location.embedded(pprofLocationLine, line.bytes)
+				locations = append(locations, location.bytes)
+			}
+
+			ids = append(ids, locationID)
+		}
+
+		var s protoBuffer
+		s.packed(pprofSampleLocationID, ids)
+		s.packed(pprofSampleValue, []uint64{uint64(sample.count)})
+		profile.embedded(pprofProfileSample, s.bytes)
+	}
+
+	for _, location := range locations {
+		profile.embedded(pprofProfileLocation, location)
+	}
+	for _, function := range functions {
+		profile.embedded(pprofProfileFunction, function)
+	}
+
+	var periodType protoBuffer
+	periodType.int64(pprofValueTypeType, table.id("instructions"))
+	periodType.int64(pprofValueTypeUnit, table.id("count"))
+
+	for _, str := range table.table {
+		profile.embedded(pprofProfileStringTable, []byte(str))
+	}
+	profile.embedded(pprofProfilePeriodType, periodType.bytes)
+	profile.int64(pprofProfilePeriod, 1)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile.bytes); err != nil {
+		return err
+	}
+	return gz.Close()
+}
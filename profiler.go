@@ -0,0 +1,251 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const mainFunction = "main"
+
+// Profiler is a Hook that counts how many times every instruction is
+// executed, together with the call stack it was executed under.
+type Profiler struct {
+	filename     string
+	instructions []Instruction
+	counts       []int
+	total        int
+	samples      map[string]*profileSample
+	key          []byte
+}
+
+type profileSample struct {
+	stack []int
+	count int
+}
+
+type profileFrame struct {
+	pc       int
+	function string
+}
+
+type profileEntry struct {
+	name      string
+	count     int
+	inclusive int
+	calls     int
+}
+
+func NewProfiler(filename string, instructions []Instruction) *Profiler {
+	return &Profiler{
+		filename:     filename,
+		instructions: instructions,
+		counts:       make([]int, len(instructions)),
+		samples:      map[string]*profileSample{},
+	}
+}
+
+func (profiler *Profiler) Before(executor *Executor, pc int) error {
+	profiler.counts[pc]++
+	profiler.total++
+
+	profiler.key = strconv.AppendInt(profiler.key[:0], int64(pc), 10)
+	for i := len(executor.callStack) - 1; i >= 0; i-- {
+		profiler.key = append(profiler.key, ',')
+		profiler.key = strconv.AppendInt(profiler.key, int64(executor.callStack[i]), 10)
+	}
+
+	sample, ok := profiler.samples[string(profiler.key)]
+	if !ok {
+		stack := make([]int, 0, len(executor.callStack)+1)
+		stack = append(stack, pc)
+		for i := len(executor.callStack) - 1; i >= 0; i-- {
+			stack = append(stack, executor.callStack[i])
+		}
+		sample = &profileSample{stack: stack}
+		profiler.samples[string(profiler.key)] = sample
+	}
+	sample.count++
+
+	return nil
+}
+
+func (profiler *Profiler) After(executor *Executor, pc int) error {
+	return nil
+}
+
+// frames resolves a sample stack, leaf first, into the functions each
+// program counter was executed in. The caller of a frame is identified by
+// the CallSubroutine instruction recorded on the call stack.
+func (profiler *Profiler) frames(stack []int) []profileFrame {
+	frames := make([]profileFrame, len(stack))
+	for i, pc := range stack {
+		function := mainFunction
+		if i+1 < len(stack) {
+			if call, ok := profiler.instructions[stack[i+1]].(CallSubroutine); ok {
+				function = LabelName(call.label)
+			}
+		}
+		frames[i] = profileFrame{pc: pc, function: function}
+	}
+	return frames
+}
+
+// enclosingLabels maps every program counter to the name of the closest
+// MarkLabel preceding it in the instruction list.
+func (profiler *Profiler) enclosingLabels() []string {
+	labels := make([]string, len(profiler.instructions))
+	current := "(none)"
+	for pc, instruction := range profiler.instructions {
+		if m, ok := instruction.(MarkLabel); ok {
+			current = LabelName(m.label)
+		}
+		labels[pc] = current
+	}
+	return labels
+}
+
+func (profiler *Profiler) labelEntries() []profileEntry {
+	labels := profiler.enclosingLabels()
+	byName := map[string]*profileEntry{}
+	var entries []*profileEntry
+	for pc, count := range profiler.counts {
+		if count == 0 {
+			continue
+		}
+		entry, ok := byName[labels[pc]]
+		if !ok {
+			entry = &profileEntry{name: labels[pc]}
+			byName[labels[pc]] = entry
+			entries = append(entries, entry)
+		}
+		entry.count += count
+	}
+	return sortEntries(entries)
+}
+
+func (profiler *Profiler) subroutineEntries() []profileEntry {
+	byName := map[string]*profileEntry{}
+	var entries []*profileEntry
+	entry := func(name string) *profileEntry {
+		e, ok := byName[name]
+		if !ok {
+			e = &profileEntry{name: name}
+			byName[name] = e
+			entries = append(entries, e)
+		}
+		return e
+	}
+
+	for _, sample := range profiler.samples {
+		frames := profiler.frames(sample.stack)
+		entry(frames[0].function).count += sample.count
+
+		seen := map[string]bool{}
+		for _, frame := range frames {
+			if seen[frame.function] {
+				continue
+			}
+			seen[frame.function] = true
+			entry(frame.function).inclusive += sample.count
+		}
+	}
+
+	for pc, count := range profiler.counts {
+		if call, ok := profiler.instructions[pc].(CallSubroutine); ok && count > 0 {
+			entry(LabelName(call.label)).calls += count
+		}
+	}
+
+	return sortEntries(entries)
+}
+
+func sortEntries(entries []*profileEntry) []profileEntry {
+	sorted := make([]profileEntry, len(entries))
+	for i, entry := range entries {
+		sorted[i] = *entry
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].inclusive != sorted[j].inclusive {
+			return sorted[i].inclusive > sorted[j].inclusive
+		}
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+func (profiler *Profiler) percent(count int) string {
+	if profiler.total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(count)*100/float64(profiler.total))
+}
+
+// WriteReport writes the per-instruction, per-label and per-subroutine
+// execution counts, sorted from the hottest entry to the coldest.
+func (profiler *Profiler) WriteReport(w io.Writer) error {
+	labels := profiler.enclosingLabels()
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Instructions executed: %d\n\n", profiler.total)
+
+	var pcs []int
+	for pc, count := range profiler.counts {
+		if count > 0 {
+			pcs = append(pcs, pc)
+		}
+	}
+	sort.SliceStable(pcs, func(i, j int) bool {
+		return profiler.counts[pcs[i]] > profiler.counts[pcs[j]]
+	})
+
+	fmt.Fprintln(tw, "count\tpercent\tpc\tinstruction\tlabel")
+	for _, pc := range pcs {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", profiler.counts[pc], profiler.percent(profiler.counts[pc]), pc, Mnemonic(profiler.instructions[pc]), labels[pc])
+	}
+
+	fmt.Fprintln(tw, "\ncount\tpercent\tlabel")
+	for _, entry := range profiler.labelEntries() {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", entry.count, profiler.percent(entry.count), entry.name)
+	}
+
+	fmt.Fprintln(tw, "\ninclusive\tpercent\texclusive\tpercent\tcalls\tsubroutine")
+	for _, entry := range profiler.subroutineEntries() {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%d\t%s\n", entry.inclusive, profiler.percent(entry.inclusive), entry.count, profiler.percent(entry.count), entry.calls, entry.name)
+	}
+
+	return tw.Flush()
+}
+
+// WriteFolded writes the samples in the folded stack format understood by
+// flame graph tools: one line per distinct chain of subroutines, root first.
+func (profiler *Profiler) WriteFolded(w io.Writer) error {
+	counts := map[string]int{}
+	for _, sample := range profiler.samples {
+		frames := profiler.frames(sample.stack)
+		names := make([]string, 0, len(frames))
+		for i := len(frames) - 1; i >= 0; i-- {
+			names = append(names, frames[i].function)
+		}
+		counts[strings.Join(names, ";")] += sample.count
+	}
+
+	stacks := make([]string, 0, len(counts))
+	for stack := range counts {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, counts[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
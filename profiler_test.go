@@ -0,0 +1,78 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func profiledSubroutineProgram() []Instruction {
+	return []Instruction{
+		CallSubroutine{label: TAB},
+		CallSubroutine{label: TAB},
+		EndProgram{},
+		MarkLabel{label: TAB},
+		Push{value: 1},
+		Discard{},
+		EndSubroutine{},
+	}
+}
+
+func TestProfilerCounts(t *testing.T) {
+	instructions := profiledSubroutineProgram()
+	profiler := NewProfiler("test.ws", instructions)
+	executor := &Executor{instructions: instructions}
+	executor.AddHook(profiler)
+
+	err := executor.Run()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 9, profiler.total)
+	assert.Equal(t, []int{1, 1, 1, 0, 2, 2, 2}, profiler.counts)
+
+	subroutines := profiler.subroutineEntries()
+	assert.Equal(t, []profileEntry{
+		{name: "main", count: 3, inclusive: 9},
+		{name: "T", count: 6, inclusive: 6, calls: 2},
+	}, subroutines)
+
+	labels := profiler.labelEntries()
+	assert.Equal(t, []profileEntry{
+		{name: "T", count: 6},
+		{name: "(none)", count: 3},
+	}, labels)
+}
+
+func TestProfilerWriteFolded(t *testing.T) {
+	instructions := profiledSubroutineProgram()
+	profiler := NewProfiler("test.ws", instructions)
+	executor := &Executor{instructions: instructions}
+	executor.AddHook(profiler)
+	executor.Run()
+
+	var out bytes.Buffer
+	profiler.WriteFolded(&out)
+
+	assert.Equal(t, "main 3\nmain;T 6\n", out.String())
+}
+
+func TestProfilerWritePprof(t *testing.T) {
+	instructions := profiledSubroutineProgram()
+	profiler := NewProfiler("test.ws", instructions)
+	executor := &Executor{instructions: instructions}
+	executor.AddHook(profiler)
+	executor.Run()
+
+	var out bytes.Buffer
+	err := profiler.WritePprof(&out)
+	assert.Nil(t, err)
+
+	reader, err := gzip.NewReader(&out)
+	assert.Nil(t, err)
+	profile, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Contains(t, string(profile), "instructions")
+	assert.Contains(t, string(profile), "test.ws")
+}
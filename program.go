@@ -0,0 +1,157 @@
+package whitespace_go
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// Program is a parsed program ready to run, for embedding the interpreter
+// in other Go programs:
+//
+//	program, err := whitespace_go.Compile(source)
+//	if err != nil {
+//		return err
+//	}
+//	result, err := program.Run(ctx, whitespace_go.WithInput(strings.NewReader("42\n")))
+//	fmt.Print(result.Output)
+//
+// A Program does not change once made, so one can be run from many
+// goroutines at a time without parsing it again.
+type Program struct {
+	instructions []Instruction
+	positions    []Position
+	labels       map[string]int
+}
+
+// Compile parses source with the default ParseOptions. The error is a
+// ParseErrors listing every error of source; RenderError shows them with
+// the lines they are on.
+func Compile(source string) (*Program, error) {
+	parser := NewParser("(program)", source)
+	if err := parser.ParseAll(); err != nil {
+		return nil, err
+	}
+	program := NewProgram(parser.Instructions)
+	program.positions = parser.Positions
+	return program, nil
+}
+
+// NewProgram returns the program made of instructions, such as those read
+// by ReadBinary or ReadJSON.
+func NewProgram(instructions []Instruction) *Program {
+	instructions = append([]Instruction{}, instructions...)
+	return &Program{instructions: instructions, labels: labelTable(instructions)}
+}
+
+// NewExecutor returns an executor for the program with an empty stack and
+// heap. Executors share the program but none of their state, so each can
+// run in its own goroutine.
+func (program *Program) NewExecutor() *Executor {
+	return &Executor{instructions: program.instructions, labels: program.labels}
+}
+
+// Instructions returns a copy of the instructions of the program.
+func (program *Program) Instructions() []Instruction {
+	return append([]Instruction{}, program.instructions...)
+}
+
+// Position returns where the instruction at pc is in the source the
+// program was compiled from, and false when the program has no source.
+func (program *Program) Position(pc int) (Position, bool) {
+	return positionAt(program.positions, pc)
+}
+
+// Result is the outcome of a run. ExitStatus is 0 when the program ended
+// and 1 when it failed, as with ws run. Output is what the program wrote,
+// unless WithOutput sent it elsewhere. Steps is the number of instructions
+// executed.
+type Result struct {
+	ExitStatus int
+	Output     string
+	Steps      int
+}
+
+// RunOption configures a run of a Program.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	input  io.Reader
+	output io.Writer
+	hooks  []Hook
+	limits Limits
+}
+
+// WithInput sets what getc and getn read. Without it the program reads an
+// empty input.
+func WithInput(r io.Reader) RunOption {
+	return func(config *runConfig) {
+		config.input = r
+	}
+}
+
+// WithOutput sends what putc and putn write to w instead of
+// Result.Output.
+func WithOutput(w io.Writer) RunOption {
+	return func(config *runConfig) {
+		config.output = w
+	}
+}
+
+// WithHook adds a Hook called around every instruction. Hooks can read the
+// state of the executor with Snapshot, and stop the run by returning an
+// error. A hook keeping state, such as a Profiler, must not be given to runs
+// happening at the same time.
+func WithHook(hook Hook) RunOption {
+	return func(config *runConfig) {
+		config.hooks = append(config.hooks, hook)
+	}
+}
+
+// WithLimits bounds the resources the program may use. A program going
+// over a limit stops with a *LimitError.
+func WithLimits(limits Limits) RunOption {
+	return func(config *runConfig) {
+		config.limits = limits
+	}
+}
+
+// WithMaxSteps stops the program with a *LimitError once it has executed n
+// instructions. Zero, the default, sets no limit.
+func WithMaxSteps(n int) RunOption {
+	return func(config *runConfig) {
+		config.limits.MaxSteps = n
+	}
+}
+
+// Run executes the program from the start with a fresh executor. The
+// error is the one the program failed with, and nil when it ended. When ctx
+// is done the program stops with a *CanceledError; see
+// Executor.RunContext.
+func (program *Program) Run(ctx context.Context, options ...RunOption) (*Result, error) {
+	config := runConfig{input: strings.NewReader("")}
+	for _, option := range options {
+		option(&config)
+	}
+
+	executor := program.NewExecutor()
+	executor.SetInput(config.input)
+	var output strings.Builder
+	if config.output != nil {
+		executor.SetOutput(config.output)
+	} else {
+		executor.SetOutput(&output)
+	}
+
+	executor.SetLimits(config.limits)
+	for _, hook := range config.hooks {
+		executor.AddHook(hook)
+	}
+
+	err := executor.RunContext(ctx)
+	result := &Result{Output: output.String(), Steps: executor.steps}
+	if err != nil {
+		result.ExitStatus = 1
+	}
+	return result, err
+}
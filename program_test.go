@@ -0,0 +1,169 @@
+package whitespace_go
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func compileAssembly(t *testing.T, assembly string) *Program {
+	instructions, err := Assemble(assembly)
+	assert.Nil(t, err)
+	program, err := Compile(Encode(instructions))
+	assert.Nil(t, err)
+	return program
+}
+
+func TestProgramRun(t *testing.T) {
+	program := compileAssembly(t, "push 0 getn push 0 retrieve push 2 mul putn end")
+
+	result, err := program.Run(context.Background(), WithInput(strings.NewReader("21\n")))
+	assert.Nil(t, err)
+	assert.Equal(t, &Result{ExitStatus: 0, Output: "42", Steps: 8}, result)
+
+	var output strings.Builder
+	result, err = program.Run(context.Background(), WithInput(strings.NewReader("-4\n")), WithOutput(&output))
+	assert.Nil(t, err)
+	assert.Equal(t, "", result.Output)
+	assert.Equal(t, "-8", output.String())
+}
+
+func TestProgramRunFailure(t *testing.T) {
+	program := compileAssembly(t, "push 1 putn add")
+
+	result, err := program.Run(context.Background())
+	assert.EqualError(t, err, "Runtime error: stack is epmty")
+	assert.Equal(t, 2, err.(*RuntimeError).PC)
+	assert.Equal(t, &Result{ExitStatus: 1, Output: "1", Steps: 2}, result)
+
+	position, ok := program.Position(2)
+	assert.True(t, ok)
+	assert.Equal(t, Position{Offset: 9, End: 13, Line: 3, Column: 3}, position)
+}
+
+func TestProgramRunDivisionByZero(t *testing.T) {
+	for _, operation := range []string{"div", "mod"} {
+		program := compileAssembly(t, "push 0 push 7 "+operation+" putn end")
+
+		result, err := program.Run(context.Background())
+		assert.EqualError(t, err, "Runtime error: division by zero")
+		assert.Equal(t, 2, err.(*RuntimeError).PC)
+		assert.Equal(t, &Result{ExitStatus: 1, Output: "", Steps: 2}, result)
+	}
+}
+
+func TestProgramRunMaxSteps(t *testing.T) {
+	program := compileAssembly(t, "label S push 1 putn jump S")
+
+	result, err := program.Run(context.Background(), WithMaxSteps(10))
+	assert.Equal(t, &LimitError{PC: 1, Limit: StepLimit, Max: 10}, err)
+	assert.EqualError(t, err, "Limit exceeded: steps above 10")
+	assert.Equal(t, &Result{ExitStatus: 1, Output: "111", Steps: 10}, result)
+}
+
+func TestProgramRunHooks(t *testing.T) {
+	program := compileAssembly(t, "push 3 push 4 add end")
+	profiler := NewProfiler("(program)", program.Instructions())
+
+	_, err := program.Run(context.Background(), WithHook(profiler))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, profiler.counts[2])
+}
+
+func TestProgramRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := compileAssembly(t, "end").Run(ctx)
+	assert.Equal(t, &CanceledError{PC: 0, Err: context.Canceled}, err)
+	assert.Equal(t, &Result{ExitStatus: 1}, result)
+}
+
+func TestProgramRunTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := compileAssembly(t, "label S push 1 discard jump S").Run(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.EqualError(t, err, "Canceled: timed out")
+	assert.Equal(t, 1, result.ExitStatus)
+	assert.Equal(t, 0, result.Steps%cancelCheckInterval)
+	assert.True(t, err.(*CanceledError).PC < 4)
+}
+
+// sumProgram prints the sum of 1 to the number it reads, with a loop and a
+// subroutine so concurrent runs share labels, and each has its own heap and
+// call stack.
+const sumProgram = `
+push 0 getn push 1 push 0 store
+label S push 0 retrieve jz T call SS jump S
+label T push 1 retrieve putn end
+label SS push 1 push 1 retrieve push 0 retrieve add store
+push 0 push 0 retrieve push -1 add store ret`
+
+func TestProgramRunConcurrently(t *testing.T) {
+	program := compileAssembly(t, sumProgram)
+
+	outputs := make([]string, 64)
+	var wait sync.WaitGroup
+	for n := range outputs {
+		wait.Add(1)
+		go func(n int) {
+			defer wait.Done()
+			input := WithInput(strings.NewReader(strconv.Itoa(n) + "\n"))
+			profiler := NewProfiler("(program)", program.Instructions())
+			result, err := program.Run(context.Background(), input, WithHook(profiler), WithMaxSteps(10000))
+			if err == nil {
+				outputs[n] = result.Output
+			}
+		}(n)
+	}
+	wait.Wait()
+
+	for n, output := range outputs {
+		assert.Equal(t, strconv.Itoa(n*(n+1)/2), output)
+	}
+}
+
+func TestProgramExecutorsAreIndependent(t *testing.T) {
+	program := compileAssembly(t, sumProgram)
+
+	executors := make([]*Executor, 8)
+	outputs := make([]strings.Builder, len(executors))
+	errs := make([]error, len(executors))
+	var wait sync.WaitGroup
+	for n := range executors {
+		executors[n] = program.NewExecutor()
+		executors[n].SetInput(strings.NewReader(strconv.Itoa(n*100) + "\n"))
+		executors[n].SetOutput(&outputs[n])
+		wait.Add(1)
+		go func(n int) {
+			defer wait.Done()
+			errs[n] = executors[n].Run()
+		}(n)
+	}
+	wait.Wait()
+
+	for n := range executors {
+		assert.Nil(t, errs[n])
+		assert.Equal(t, strconv.Itoa(n*100*(n*100+1)/2), outputs[n].String())
+		assert.Equal(t, 0, len(executors[n].callStack))
+	}
+}
+
+func TestInstructionOperands(t *testing.T) {
+	instructions, err := Assemble("push -7 label ST call ST jump ST jz ST jn ST")
+	assert.Nil(t, err)
+
+	assert.Equal(t, -7, instructions[0].(Push).Value())
+	assert.Equal(t, SPACE+TAB, instructions[1].(MarkLabel).Label())
+	assert.Equal(t, SPACE+TAB, instructions[2].(CallSubroutine).Label())
+	assert.Equal(t, SPACE+TAB, instructions[3].(JumpLabel).Label())
+	assert.Equal(t, SPACE+TAB, instructions[4].(JumpLabelWhenZero).Label())
+	assert.Equal(t, SPACE+TAB, instructions[5].(JumpLabelWhenNegative).Label())
+}
@@ -0,0 +1,168 @@
+package whitespace_go
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+const replHelp = `Enter assembly (push 1 push 2 add putn) or Whitespace written with the
+letters S, T and L (S S S T L). Every entry is executed right away against
+the same stack and heap, and labels stay defined for later entries.
+
+  :def ENTRY   add ENTRY to the session without executing it
+  :load FILE   parse FILE, add it to the session and execute it
+  :stack       show the stack, bottom first
+  :heap        show the heap
+  :list        show every instruction in the session
+  :reset       forget all instructions and state
+  :help        show this help
+  :quit        leave the REPL
+`
+
+var errQuit = errors.New("quit")
+
+// REPL executes Whitespace entered line by line against one Executor.
+type REPL struct {
+	in       *bufio.Reader
+	out      io.Writer
+	executor *Executor
+}
+
+func NewREPL(in io.Reader, out io.Writer) *REPL {
+	repl := &REPL{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+	repl.reset()
+	return repl
+}
+
+func (repl *REPL) reset() {
+	repl.executor = &Executor{
+		heap:   map[int]int{},
+		stdin:  repl.in,
+		stdout: repl.out,
+	}
+}
+
+func (repl *REPL) Run() {
+	fmt.Fprintln(repl.out, "Type :help for help.")
+
+	for {
+		fmt.Fprint(repl.out, "ws> ")
+		line, err := repl.in.ReadString('\n')
+		if line == "" && err != nil {
+			fmt.Fprintln(repl.out)
+			return
+		}
+
+		if errEval := repl.Eval(line); errEval == errQuit {
+			return
+		} else if errEval != nil {
+			fmt.Fprintln(repl.out, RenderError(errEval))
+		}
+	}
+}
+
+// Eval runs one line of input, either a command starting with ':' or
+// instructions to execute.
+func (repl *REPL) Eval(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(line, ":") {
+		return repl.command(line)
+	}
+
+	instructions, err := repl.compile(line)
+	if err != nil {
+		return err
+	}
+
+	return repl.execute(instructions)
+}
+
+func (repl *REPL) command(line string) error {
+	name, argument := line, ""
+	if space := strings.IndexAny(line, " \t"); space >= 0 {
+		name, argument = line[:space], strings.TrimSpace(line[space+1:])
+	}
+
+	switch name {
+	case ":def":
+		instructions, err := repl.compile(argument)
+		if err != nil {
+			return err
+		}
+		repl.executor.instructions = append(repl.executor.instructions, instructions...)
+		repl.executor.labels = nil
+	case ":load":
+		bytes, err := ioutil.ReadFile(argument)
+		if err != nil {
+			return fmt.Errorf("%s can not read", argument)
+		}
+		parser := NewParser(argument, string(bytes))
+		if err := parser.ParseAll(); err != nil {
+			return err
+		}
+		return repl.execute(parser.Instructions)
+	case ":stack":
+		fmt.Fprintln(repl.out, repl.executor.stack)
+	case ":heap":
+		addresses := make([]int, 0, len(repl.executor.heap))
+		for address := range repl.executor.heap {
+			addresses = append(addresses, address)
+		}
+		sort.Ints(addresses)
+		for _, address := range addresses {
+			fmt.Fprintf(repl.out, "%d: %d\n", address, repl.executor.heap[address])
+		}
+	case ":list":
+		for pc, instruction := range repl.executor.instructions {
+			fmt.Fprintf(repl.out, "%4d  %s\n", pc, Mnemonic(instruction))
+		}
+	case ":reset":
+		repl.reset()
+	case ":help":
+		fmt.Fprint(repl.out, replHelp)
+	case ":quit":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %s, type :help for help", name)
+	}
+
+	return nil
+}
+
+func (repl *REPL) compile(entry string) ([]Instruction, error) {
+	if !isSTL(entry) {
+		return Assemble(entry)
+	}
+
+	parser := NewParser("(repl)", STLNotation.Decode(entry))
+	err := parser.ParseAll()
+	return parser.Instructions, err
+}
+
+// execute appends instructions to the session and runs them. Earlier
+// entries are not run again, but stay reachable through their labels.
+func (repl *REPL) execute(instructions []Instruction) error {
+	start := len(repl.executor.instructions)
+	repl.executor.instructions = append(repl.executor.instructions, instructions...)
+	repl.executor.labels = nil
+	return repl.executor.runFrom(context.Background(), start)
+}
+
+// isSTL reports whether an entry is written with the letters S, T and L,
+// which no mnemonic is made of.
+func isSTL(entry string) bool {
+	return strings.ContainsAny(entry, "STL") && strings.Trim(entry, "STL \t\r\n") == ""
+}
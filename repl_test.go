@@ -0,0 +1,104 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	instructions, err := Assemble("push -3 dup swap discard ; comment\nadd sub mul div mod\nstore retrieve putc putn getc getn\nlabel ST call T jump S jz TS jn TT ret end")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Instruction{
+		Push{value: -3}, Duplicate{}, Swap{}, Discard{},
+		Addition{}, Subtraction{}, Multiplication{}, Division{}, Modulo{},
+		Store{}, Retrieve{}, Putc{}, Putn{}, Getc{}, Getn{},
+		MarkLabel{label: SPACE + TAB},
+		CallSubroutine{label: TAB},
+		JumpLabel{label: SPACE},
+		JumpLabelWhenZero{label: TAB + SPACE},
+		JumpLabelWhenNegative{label: TAB + TAB},
+		EndSubroutine{},
+		EndProgram{},
+	}, instructions)
+}
+
+func TestAssembleErrors(t *testing.T) {
+	for _, source := range []string{"push", "push x", "jump SX", "frobnicate 1"} {
+		_, err := Assemble(source)
+		assert.NotNil(t, err, source)
+	}
+}
+
+func TestAssembleMnemonicRoundTrip(t *testing.T) {
+	instructions := profiledSubroutineProgram()
+
+	assembled, err := Assemble(Disassemble(instructions))
+
+	assert.Nil(t, err)
+	assert.Equal(t, instructions, assembled)
+}
+
+func TestREPLKeepsStateAcrossEntries(t *testing.T) {
+	var out bytes.Buffer
+	repl := NewREPL(strings.NewReader(""), &out)
+
+	assert.Nil(t, repl.Eval("push 1 push 2"))
+	assert.Nil(t, repl.Eval("S S S T T L"))
+	assert.Equal(t, []int{1, 2, 3}, repl.executor.stack)
+
+	assert.Nil(t, repl.Eval(":def label T push 10 add ret"))
+	assert.Equal(t, []int{1, 2, 3}, repl.executor.stack)
+
+	assert.Nil(t, repl.Eval("call T"))
+	assert.Equal(t, []int{1, 2, 13}, repl.executor.stack)
+
+	assert.Nil(t, repl.Eval(":def label S discard ret"))
+	assert.Nil(t, repl.Eval("call S"))
+	assert.Equal(t, []int{1, 2}, repl.executor.stack)
+	assert.Nil(t, repl.Eval("push 13"))
+
+	assert.Nil(t, repl.Eval("push 7 swap store"))
+	assert.Nil(t, repl.Eval(":heap"))
+	assert.Equal(t, "7: 13\n", out.String())
+
+	assert.Nil(t, repl.Eval(":reset"))
+	assert.Empty(t, repl.executor.stack)
+	assert.Empty(t, repl.executor.instructions)
+}
+
+func TestREPLWritesProgramOutput(t *testing.T) {
+	var out bytes.Buffer
+	repl := NewREPL(strings.NewReader(""), &out)
+
+	assert.Nil(t, repl.Eval("push 42 putn push 10 putc"))
+	assert.Equal(t, "42\n", out.String())
+}
+
+type brokenReader struct{}
+
+func (r brokenReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestREPLReportsInputErrors(t *testing.T) {
+	var out bytes.Buffer
+	repl := NewREPL(brokenReader{}, &out)
+	assert.EqualError(t, repl.Eval("push 0 getc"), "Runtime error: can not read input: broken pipe")
+
+	repl = NewREPL(strings.NewReader(""), &out)
+	assert.EqualError(t, repl.Eval("push 0 getn"), "Runtime error: input is empty")
+}
+
+func TestREPLReportsErrors(t *testing.T) {
+	var out bytes.Buffer
+	repl := NewREPL(strings.NewReader(""), &out)
+
+	assert.NotNil(t, repl.Eval("add"))
+	assert.NotNil(t, repl.Eval("S S"))
+	assert.NotNil(t, repl.Eval(":nope"))
+	assert.Equal(t, errQuit, repl.Eval(":quit"))
+}
@@ -0,0 +1,179 @@
+package whitespace_go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+const snapshotVersion = 1
+
+// Snapshot is the complete state of an Executor between two instructions.
+// ProgramCounter is the next instruction to execute and InputPosition the
+// number of input bytes Getc and Getn have consumed so far.
+type Snapshot struct {
+	Version        int         `json:"version"`
+	Program        string      `json:"program"`
+	ProgramCounter int         `json:"program_counter"`
+	Stack          []int       `json:"stack"`
+	Heap           map[int]int `json:"heap"`
+	CallStack      []int       `json:"call_stack"`
+	InputPosition  int64       `json:"input_position"`
+}
+
+// Snapshot captures the current state of the executor.
+func (executor *Executor) Snapshot() Snapshot {
+	heap := make(map[int]int, len(executor.heap))
+	for address, value := range executor.heap {
+		heap[address] = value
+	}
+
+	return Snapshot{
+		Version:        snapshotVersion,
+		Program:        programHash(executor.instructions),
+		ProgramCounter: executor.programCounter,
+		Stack:          append([]int{}, executor.stack...),
+		Heap:           heap,
+		CallStack:      append([]int{}, executor.callStack...),
+		InputPosition:  executor.inputOffset,
+	}
+}
+
+// Resume restores snapshot and continues execution from it. The snapshot
+// must have been taken from the same program. Input set with SetInput is
+// expected to start where the snapshot stopped reading; see SkipInput.
+func (executor *Executor) Resume(snapshot Snapshot) error {
+	return executor.ResumeContext(context.Background(), snapshot)
+}
+
+// ResumeContext is Resume stopping once ctx is done, as RunContext does.
+func (executor *Executor) ResumeContext(ctx context.Context, snapshot Snapshot) error {
+	if snapshot.Program != programHash(executor.instructions) {
+		return &RuntimeError{PC: -1, Message: "snapshot was taken from a different program"}
+	}
+	if snapshot.ProgramCounter < 0 || snapshot.ProgramCounter > len(executor.instructions) {
+		return &RuntimeError{PC: -1, Message: "snapshot program counter is out of range"}
+	}
+	for _, counter := range snapshot.CallStack {
+		if counter < 0 || counter >= len(executor.instructions) {
+			return &RuntimeError{PC: -1, Message: "snapshot call stack is out of range"}
+		}
+	}
+
+	executor.stack = append([]int{}, snapshot.Stack...)
+	executor.callStack = append([]int{}, snapshot.CallStack...)
+	executor.inputOffset = snapshot.InputPosition
+	executor.heap = make(map[int]int, len(snapshot.Heap))
+	for address, value := range snapshot.Heap {
+		executor.heap[address] = value
+	}
+
+	return executor.runFrom(ctx, snapshot.ProgramCounter)
+}
+
+// SkipInput discards the input the snapshot had already consumed from r, so
+// the same input file can be replayed when resuming.
+func SkipInput(r io.Reader, snapshot Snapshot) error {
+	_, err := io.CopyN(ioutil.Discard, r, snapshot.InputPosition)
+	return err
+}
+
+func WriteSnapshot(w io.Writer, snapshot Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot: %s", err.Error())
+	}
+
+	if snapshot.Version != snapshotVersion {
+		return snapshot, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	return snapshot, nil
+}
+
+// SnapshotRecorder is a Hook that remembers enough of the state before every
+// instruction to undo what a failing instruction did before it failed, so
+// the snapshot taken after a runtime error points at the failing instruction
+// with the stack it started with. Calling Interrupt makes the executor stop
+// before the next instruction with a resumable state. It must be the first
+// hook added so it sees every instruction before the other hooks can stop
+// the executor.
+type SnapshotRecorder struct {
+	started     bool
+	steps       int
+	completed   bool
+	depth       int
+	top         [2]int
+	callDepth   int
+	inputOffset int64
+	interrupted int32
+}
+
+func (recorder *SnapshotRecorder) Before(executor *Executor, pc int) error {
+	recorder.started = true
+	recorder.steps = executor.steps
+	recorder.completed = false
+	recorder.depth = len(executor.stack)
+	for i := 0; i < len(recorder.top) && i < recorder.depth; i++ {
+		recorder.top[i] = executor.stack[recorder.depth-1-i]
+	}
+	recorder.callDepth = len(executor.callStack)
+	recorder.inputOffset = executor.inputOffset
+
+	if atomic.LoadInt32(&recorder.interrupted) != 0 {
+		return runtimeError(executor, "interrupted")
+	}
+
+	return nil
+}
+
+func (recorder *SnapshotRecorder) After(executor *Executor, pc int) error {
+	recorder.completed = true
+	return nil
+}
+
+func (recorder *SnapshotRecorder) Interrupt() {
+	atomic.StoreInt32(&recorder.interrupted, 1)
+}
+
+// Snapshot returns a resumable state after the executor stopped. When it
+// stopped between two instructions, on cancellation or at the step limit,
+// that is the current state. When the current instruction completed and a
+// later hook stopped the executor, that is the state after it. Otherwise it
+// is the state from before the instruction started: instructions pop at
+// most two values and only push once they succeed, so restoring the saved
+// top of the stack is enough.
+func (recorder *SnapshotRecorder) Snapshot(executor *Executor) Snapshot {
+	snapshot := executor.Snapshot()
+	if !recorder.started || executor.steps != recorder.steps {
+		return snapshot
+	}
+	if recorder.completed {
+		snapshot.ProgramCounter++
+		return snapshot
+	}
+
+	if len(snapshot.Stack) <= recorder.depth {
+		stack := snapshot.Stack
+		for i := recorder.depth - len(stack) - 1; i >= 0; i-- {
+			stack = append(stack, recorder.top[i])
+		}
+		snapshot.Stack = stack
+	}
+
+	if len(snapshot.CallStack) > recorder.callDepth {
+		snapshot.CallStack = snapshot.CallStack[:recorder.callDepth]
+	}
+	snapshot.InputPosition = recorder.inputOffset
+
+	return snapshot
+}
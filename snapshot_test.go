@@ -0,0 +1,182 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func inputProgram() []Instruction {
+	return []Instruction{
+		Push{value: 0},
+		Getn{},
+		Push{value: 0},
+		Retrieve{},
+		Push{value: 1},
+		Addition{},
+		EndProgram{},
+	}
+}
+
+func TestSnapshotOnErrorResumes(t *testing.T) {
+	recorder := &SnapshotRecorder{}
+	executor := &Executor{instructions: inputProgram()}
+	executor.AddHook(recorder)
+	executor.SetInput(strings.NewReader("x\n"))
+
+	err := executor.Run()
+	assert.NotNil(t, err)
+
+	snapshot := recorder.Snapshot(executor)
+	assert.Equal(t, 1, snapshot.ProgramCounter)
+	assert.Equal(t, []int{0}, snapshot.Stack)
+	assert.Equal(t, int64(0), snapshot.InputPosition)
+
+	var file bytes.Buffer
+	WriteSnapshot(&file, snapshot)
+	restored, err := ReadSnapshot(&file)
+	assert.Nil(t, err)
+
+	resumed := &Executor{instructions: inputProgram()}
+	resumed.SetInput(strings.NewReader("41\n"))
+	err = resumed.Resume(restored)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int{42}, resumed.stack)
+	assert.Equal(t, map[int]int{0: 41}, resumed.heap)
+	assert.Equal(t, int64(3), resumed.inputOffset)
+}
+
+func TestSnapshotUndoesFailedInstruction(t *testing.T) {
+	recorder := &SnapshotRecorder{}
+	executor := &Executor{instructions: []Instruction{Push{value: 5}, Push{value: 7}, Addition{}, Addition{}}}
+	executor.AddHook(recorder)
+
+	err := executor.Run()
+	assert.NotNil(t, err)
+	assert.Empty(t, executor.stack)
+
+	snapshot := recorder.Snapshot(executor)
+	assert.Equal(t, 3, snapshot.ProgramCounter)
+	assert.Equal(t, []int{12}, snapshot.Stack)
+}
+
+// countProgram prints the numbers 1 to n.
+func countProgram(t *testing.T, n int) []Instruction {
+	instructions, err := Assemble("push 0 label S push 1 add dup putn dup push " + strconv.Itoa(n) + " sub jz T jump S label T discard end")
+	assert.Nil(t, err)
+	return instructions
+}
+
+// resumeOutput resumes snapshot and returns what the rest of the run prints.
+// A resumed run that skipped an instruction may not end, so it is limited.
+func resumeOutput(t *testing.T, instructions []Instruction, snapshot Snapshot) string {
+	var output strings.Builder
+	resumed := &Executor{instructions: instructions}
+	resumed.SetOutput(&output)
+	resumed.SetLimits(Limits{MaxSteps: 10000})
+	assert.Nil(t, resumed.Resume(snapshot))
+	return output.String()
+}
+
+func TestSnapshotAtStepLimitResumes(t *testing.T) {
+	instructions := countProgram(t, 5)
+	var full strings.Builder
+	executor := &Executor{instructions: instructions}
+	executor.SetOutput(&full)
+	assert.Nil(t, executor.Run())
+
+	for max := 1; max < executor.steps; max++ {
+		var output strings.Builder
+		recorder := &SnapshotRecorder{}
+		limited := &Executor{instructions: instructions}
+		limited.AddHook(recorder)
+		limited.SetOutput(&output)
+		limited.SetLimits(Limits{MaxSteps: max})
+
+		err := limited.Run()
+		assert.Equal(t, &LimitError{PC: limited.programCounter, Limit: StepLimit, Max: max}, err)
+
+		snapshot := recorder.Snapshot(limited)
+		assert.Equal(t, limited.programCounter, snapshot.ProgramCounter)
+		assert.Equal(t, full.String(), output.String()+resumeOutput(t, instructions, snapshot), "max steps %d", max)
+	}
+}
+
+type cancelAfter struct {
+	steps  int
+	cancel context.CancelFunc
+}
+
+func (c *cancelAfter) Before(executor *Executor, pc int) error { return nil }
+
+func (c *cancelAfter) After(executor *Executor, pc int) error {
+	if executor.steps+1 == c.steps {
+		c.cancel()
+	}
+	return nil
+}
+
+func TestSnapshotAfterCancelResumes(t *testing.T) {
+	instructions := countProgram(t, 300)
+	var full strings.Builder
+	executor := &Executor{instructions: instructions}
+	executor.SetOutput(&full)
+	assert.Nil(t, executor.Run())
+
+	for _, steps := range []int{0, 1000} {
+		ctx, cancel := context.WithCancel(context.Background())
+		if steps == 0 {
+			cancel()
+		}
+
+		var output strings.Builder
+		recorder := &SnapshotRecorder{}
+		canceled := &Executor{instructions: instructions}
+		canceled.AddHook(recorder)
+		canceled.AddHook(&cancelAfter{steps: steps, cancel: cancel})
+		canceled.SetOutput(&output)
+
+		err := canceled.RunContext(ctx)
+		assert.IsType(t, &CanceledError{}, err)
+		cancel()
+
+		snapshot := recorder.Snapshot(canceled)
+		assert.Equal(t, full.String(), output.String()+resumeOutput(t, instructions, snapshot), "canceled after %d steps", steps)
+	}
+}
+
+func TestSnapshotSkipInput(t *testing.T) {
+	input := strings.NewReader("1\n2\n")
+
+	err := SkipInput(input, Snapshot{InputPosition: 2})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, input.Len())
+}
+
+func TestSnapshotRejectsOtherProgram(t *testing.T) {
+	snapshot := (&Executor{instructions: inputProgram()}).Snapshot()
+
+	err := (&Executor{instructions: []Instruction{EndProgram{}}}).Resume(snapshot)
+	assert.NotNil(t, err)
+
+	_, err = ReadSnapshot(strings.NewReader(`{"version": 99}`))
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotRejectsOutOfRangeState(t *testing.T) {
+	snapshot := (&Executor{instructions: inputProgram()}).Snapshot()
+
+	snapshot.ProgramCounter = len(inputProgram()) + 1
+	err := (&Executor{instructions: inputProgram()}).Resume(snapshot)
+	assert.Equal(t, &RuntimeError{PC: -1, Message: "snapshot program counter is out of range"}, err)
+
+	snapshot.ProgramCounter = 0
+	snapshot.CallStack = []int{2, len(inputProgram())}
+	err = (&Executor{instructions: inputProgram()}).Resume(snapshot)
+	assert.Equal(t, &RuntimeError{PC: -1, Message: "snapshot call stack is out of range"}, err)
+}
@@ -0,0 +1,328 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// linear is the value constant + Σ terms[v]·v over symbolic variables v.
+// Coefficients are never zero.
+type linear struct {
+	constant int
+	terms    map[int]int
+}
+
+func constantLinear(n int) linear {
+	return linear{constant: n}
+}
+
+func variableLinear(v int) linear {
+	return linear{terms: map[int]int{v: 1}}
+}
+
+func (a linear) isConstant() bool {
+	return len(a.terms) == 0
+}
+
+func (a linear) add(b linear) linear {
+	sum := linear{constant: a.constant + b.constant, terms: map[int]int{}}
+	for v, k := range a.terms {
+		sum.terms[v] = k
+	}
+	for v, k := range b.terms {
+		if sum.terms[v] += k; sum.terms[v] == 0 {
+			delete(sum.terms, v)
+		}
+	}
+	return sum
+}
+
+func (a linear) scale(k int) linear {
+	if k == 0 {
+		return constantLinear(0)
+	}
+	scaled := linear{constant: a.constant * k, terms: map[int]int{}}
+	for v, c := range a.terms {
+		scaled.terms[v] = c * k
+	}
+	return scaled
+}
+
+func (a linear) sub(b linear) linear {
+	return a.add(b.scale(-1))
+}
+
+// variables returns the variables of a in increasing order.
+func (a linear) variables() []int {
+	variables := make([]int, 0, len(a.terms))
+	for v := range a.terms {
+		variables = append(variables, v)
+	}
+	sort.Ints(variables)
+	return variables
+}
+
+func (a linear) eval(model []int) int {
+	value := a.constant
+	for v, k := range a.terms {
+		value += k * model[v]
+	}
+	return value
+}
+
+func (a linear) String() string {
+	var parts []string
+	for _, v := range a.variables() {
+		parts = append(parts, fmt.Sprintf("%d*x%d", a.terms[v], v))
+	}
+	if a.constant != 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprint(a.constant))
+	}
+	return strings.Join(parts, " + ")
+}
+
+type relation int
+
+const (
+	relationZero relation = iota
+	relationNonZero
+	relationNegative
+	relationNonNegative
+)
+
+// constraint requires value to be zero, non-zero, negative or non-negative.
+type constraint struct {
+	value    linear
+	relation relation
+}
+
+func (c constraint) holds(model []int) bool {
+	value := c.value.eval(model)
+	switch c.relation {
+	case relationZero:
+		return value == 0
+	case relationNonZero:
+		return value != 0
+	case relationNegative:
+		return value < 0
+	default:
+		return value >= 0
+	}
+}
+
+// symbolicVariable is the domain [lo, hi] of a variable and the value the
+// solver tries first.
+type symbolicVariable struct {
+	lo, hi, prefer int
+}
+
+// solverBudget bounds the number of search nodes a single solve may visit.
+const solverBudget = 20000
+
+type solver struct {
+	constraints []constraint
+	prefer      []int
+	budget      int
+}
+
+// solve looks for values of the variables that satisfy every constraint.
+// Bounds are propagated through the constraints and the domains bisected
+// until the preferred values clamped to the domains are a solution. It gives
+// up after solverBudget nodes, so false means no solution was found, not
+// that none exists.
+func solve(variables []symbolicVariable, constraints []constraint) ([]int, bool) {
+	lo := make([]int, len(variables))
+	hi := make([]int, len(variables))
+	s := &solver{constraints: constraints, prefer: make([]int, len(variables)), budget: solverBudget}
+	for v, variable := range variables {
+		lo[v], hi[v], s.prefer[v] = variable.lo, variable.hi, variable.prefer
+	}
+	return s.search(lo, hi)
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
+
+func (s *solver) search(lo, hi []int) ([]int, bool) {
+	if s.budget <= 0 {
+		return nil, false
+	}
+	s.budget--
+
+	if !s.propagate(lo, hi) {
+		return nil, false
+	}
+
+	model := make([]int, len(lo))
+	for v := range model {
+		model[v] = clamp(s.prefer[v], lo[v], hi[v])
+	}
+
+	split := -1
+	for _, c := range s.constraints {
+		if c.holds(model) {
+			continue
+		}
+		for _, v := range c.value.variables() {
+			if hi[v] > lo[v] && (split < 0 || hi[v]-lo[v] > hi[split]-lo[split]) {
+				split = v
+			}
+		}
+		if split < 0 {
+			return nil, false
+		}
+		break
+	}
+	if split < 0 {
+		return model, true
+	}
+
+	mid := lo[split] + (hi[split]-lo[split])/2
+	halves := [][2]int{{lo[split], mid}, {mid + 1, hi[split]}}
+	if model[split] > mid {
+		halves[0], halves[1] = halves[1], halves[0]
+	}
+	for _, half := range halves {
+		l := append([]int{}, lo...)
+		h := append([]int{}, hi...)
+		l[split], h[split] = half[0], half[1]
+		if model, ok := s.search(l, h); ok {
+			return model, true
+		}
+	}
+	return nil, false
+}
+
+// multiply multiplies a coefficient by a bound, saturating at unbounded.
+func multiply(k, n int) int {
+	if k == 0 || n == 0 {
+		return 0
+	}
+	negative := (k < 0) != (n < 0)
+	if abs(n) >= unbounded || abs(k) > unbounded/abs(n) {
+		if negative {
+			return -unbounded
+		}
+		return unbounded
+	}
+	return saturate(k * n)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func ceilDiv(a, b int) int {
+	return -floorDiv(-a, b)
+}
+
+// rest returns the range of value without the term of variable skip.
+func rest(value linear, skip int, lo, hi []int) (int, int) {
+	min, max := saturate(value.constant), saturate(value.constant)
+	for v, k := range value.terms {
+		if v == skip {
+			continue
+		}
+		if k > 0 {
+			min, max = saturate(min+multiply(k, lo[v])), saturate(max+multiply(k, hi[v]))
+		} else {
+			min, max = saturate(min+multiply(k, hi[v])), saturate(max+multiply(k, lo[v]))
+		}
+	}
+	return min, max
+}
+
+// propagate narrows the domains to the values that can still satisfy every
+// constraint and reports false when one of them is left empty.
+func (s *solver) propagate(lo, hi []int) bool {
+	for round := 0; round < 64; round++ {
+		changed := false
+		narrow := func(v, l, h int) {
+			if l > lo[v] {
+				lo[v], changed = l, true
+			}
+			if h < hi[v] {
+				hi[v], changed = h, true
+			}
+		}
+
+		for _, c := range s.constraints {
+			min, max := rest(c.value, -1, lo, hi)
+			switch {
+			case c.relation == relationZero && (min > 0 || max < 0),
+				c.relation == relationNonZero && min == 0 && max == 0,
+				c.relation == relationNegative && min >= 0,
+				c.relation == relationNonNegative && max < 0:
+				return false
+			}
+
+			for v, k := range c.value.terms {
+				min, max := rest(c.value, v, lo, hi)
+
+				// The term k·v must lie in [l, h].
+				l, h := -unbounded, unbounded
+				switch c.relation {
+				case relationZero:
+					l, h = -max, -min
+				case relationNegative:
+					h = -1 - min
+				case relationNonNegative:
+					l = -max
+				case relationNonZero:
+					if min == max && min%k == 0 {
+						excluded := -min / k
+						if lo[v] == excluded {
+							narrow(v, excluded+1, hi[v])
+						}
+						if hi[v] == excluded {
+							narrow(v, lo[v], excluded-1)
+						}
+					}
+					continue
+				}
+
+				if k < 0 {
+					l, h = -h, -l
+					k = -k
+				}
+				if l > -unbounded && h < unbounded {
+					narrow(v, ceilDiv(l, k), floorDiv(h, k))
+				} else if l > -unbounded {
+					narrow(v, ceilDiv(l, k), hi[v])
+				} else if h < unbounded {
+					narrow(v, lo[v], floorDiv(h, k))
+				}
+			}
+
+			for v := range c.value.terms {
+				if lo[v] > hi[v] {
+					return false
+				}
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+	return true
+}
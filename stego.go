@@ -0,0 +1,162 @@
+package whitespace_go
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// noBreakSpace separates carrier words where the program has no token that
+// fits. It is a comment to the parser.
+const noBreakSpace = "\u00A0"
+
+// embedder interleaves the tokens of a program into the whitespace of a
+// carrier text.
+type embedder struct {
+	builder strings.Builder
+	tokens  []rune
+	next    int
+	// endLeft is how many LFs of the end instruction written after the
+	// program are still to come.
+	endLeft  int
+	needsEnd bool
+	// padding is how many spaces of the current padding push are written.
+	padding int
+	pushes  int
+}
+
+// Embed hides program in carrier. Every space, tab and linefeed of the
+// carrier is replaced: a space between words by the next token of the
+// program when it is a space, a tab by a space or tab, and a linefeed by the
+// tokens up to the next LF of the program, which end up as trailing
+// whitespace. A gap no token fits is filled with a no-break space, and
+// tokens left over are appended after the carrier. When the carrier has more
+// lines than the program, the rest of them is padded with push 0, after an
+// end if the program could fall through to them. The result is checked to
+// parse to the program followed by the padding.
+func Embed(carrier string, program string) (string, error) {
+	parser := NewParser("(program)", program)
+	if err := parser.ParseAll(); err != nil {
+		return "", err
+	}
+
+	embedder := &embedder{tokens: instructionTokens(program)}
+	if n := len(parser.Instructions); n == 0 || fallsThrough(parser.Instructions[n-1]) {
+		embedder.endLeft = len(commandOf(EndProgram{}).code)
+		embedder.needsEnd = true
+	}
+
+	for _, r := range strings.Replace(carrier, "\r\n", LF, -1) {
+		switch string(r) {
+		case SPACE:
+			embedder.gap(false)
+		case TAB:
+			embedder.gap(true)
+		case LF:
+			embedder.lineBreak()
+		default:
+			embedder.builder.WriteRune(r)
+		}
+	}
+	embedder.finish()
+
+	expected := append([]Instruction{}, parser.Instructions...)
+	if embedder.needsEnd && embedder.endLeft == 0 {
+		expected = append(expected, EndProgram{})
+	}
+	for n := 0; n < embedder.pushes; n++ {
+		expected = append(expected, Push{value: 0})
+	}
+
+	embedded := embedder.builder.String()
+	check := NewParser("(embedded)", embedded)
+	if err := check.ParseAll(); err != nil || !reflect.DeepEqual(check.Instructions, expected) {
+		return "", errors.New("the carrier corrupts the program")
+	}
+	return embedded, nil
+}
+
+// Extract returns the tokens of text, which is the program Embed hid in it.
+func Extract(text string) string {
+	return string(instructionTokens(text))
+}
+
+// fallsThrough reports whether the instruction after instruction may run
+// after it.
+func fallsThrough(instruction Instruction) bool {
+	switch instruction.(type) {
+	case EndProgram, EndSubroutine, JumpLabel:
+		return false
+	default:
+		return true
+	}
+}
+
+func (embedder *embedder) exhausted() bool {
+	return embedder.next >= len(embedder.tokens)
+}
+
+func (embedder *embedder) emit() rune {
+	token := embedder.tokens[embedder.next]
+	embedder.builder.WriteRune(token)
+	embedder.next++
+	return token
+}
+
+func (embedder *embedder) gap(tab bool) {
+	if !embedder.exhausted() {
+		token := string(embedder.tokens[embedder.next])
+		if token == SPACE || (tab && token == TAB) {
+			embedder.emit()
+		} else {
+			embedder.builder.WriteString(noBreakSpace)
+		}
+		return
+	}
+
+	if embedder.endLeft > 0 {
+		embedder.builder.WriteString(noBreakSpace)
+		return
+	}
+	embedder.builder.WriteString(SPACE)
+	embedder.padding++
+}
+
+func (embedder *embedder) lineBreak() {
+	for !embedder.exhausted() {
+		if string(embedder.emit()) == LF {
+			return
+		}
+	}
+
+	if embedder.endLeft > 0 {
+		embedder.builder.WriteString(LF)
+		embedder.endLeft--
+		return
+	}
+	embedder.endPush()
+}
+
+// endPush ends the padding push 0 being written, which needs two spaces for
+// the command, one for the sign and at least one digit.
+func (embedder *embedder) endPush() {
+	if embedder.padding < 4 {
+		embedder.builder.WriteString(strings.Repeat(SPACE, 4-embedder.padding))
+	}
+	embedder.builder.WriteString(LF)
+	embedder.pushes++
+	embedder.padding = 0
+}
+
+func (embedder *embedder) finish() {
+	for !embedder.exhausted() {
+		embedder.emit()
+	}
+	if embedder.endLeft > 0 && embedder.endLeft < len(commandOf(EndProgram{}).code) {
+		embedder.builder.WriteString(strings.Repeat(LF, embedder.endLeft))
+		embedder.endLeft = 0
+	}
+	if embedder.padding > 0 {
+		embedder.endPush()
+	}
+}
@@ -0,0 +1,39 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	program := SPACE + SPACE + SPACE + TAB + LF + TAB + LF + SPACE + TAB + LF + LF + LF
+	embedded, err := Embed("one two\nthree four five\r\nsix", program)
+	assert.Nil(t, err)
+
+	expected := "one" + SPACE + "two" + SPACE + SPACE + TAB + LF +
+		"three" + noBreakSpace + "four" + noBreakSpace + "five" + TAB + LF +
+		"six" + SPACE + TAB + LF + LF + LF
+	assert.Equal(t, expected, embedded)
+	assert.Equal(t, program, Extract(embedded))
+}
+
+func TestEmbedPadsLongCarriers(t *testing.T) {
+	program := SPACE + SPACE + SPACE + TAB + LF
+	embedded, err := Embed("a\nb c\nd e f g h\ni\nj k", program)
+	assert.Nil(t, err)
+
+	parser := NewParser("embedded.ws", embedded)
+	assert.Nil(t, parser.ParseAll())
+	assert.Equal(t, []Instruction{Push{value: 1}, EndProgram{}, Push{value: 0}}, parser.Instructions)
+	assert.Equal(t, "a"+SPACE+SPACE+SPACE+TAB+LF+
+		"b"+noBreakSpace+"c"+LF+
+		"d"+noBreakSpace+"e"+noBreakSpace+"f"+noBreakSpace+"g"+noBreakSpace+"h"+LF+
+		"i"+LF+
+		"j"+SPACE+"k"+SPACE+SPACE+SPACE+LF+
+		"", embedded)
+}
+
+func TestEmbedRejectsInvalidPrograms(t *testing.T) {
+	_, err := Embed("carrier", SPACE+TAB)
+	assert.Equal(t, "Parse error: expected stack manipulation command at (program):1:2", err.Error())
+}
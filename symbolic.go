@@ -0,0 +1,569 @@
+package whitespace_go
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type FindingKind int
+
+const (
+	FindingTarget FindingKind = iota
+	FindingRuntimeError
+	FindingDivisionByZero
+)
+
+func (kind FindingKind) String() string {
+	switch kind {
+	case FindingRuntimeError:
+		return "runtime-error"
+	case FindingDivisionByZero:
+		return "division-by-zero"
+	default:
+		return "target"
+	}
+}
+
+// Finding is an input that makes the program reach the target label, fail
+// with a runtime error or divide by zero at PC. Input holds one line per
+// Getc or Getn, in the order they read them.
+type Finding struct {
+	Kind    FindingKind
+	PC      int
+	Message string
+	Input   []string
+}
+
+// InputText returns the input of the finding as it would be typed.
+func (finding Finding) InputText() string {
+	if len(finding.Input) == 0 {
+		return ""
+	}
+	return strings.Join(finding.Input, "\n") + "\n"
+}
+
+// SymbolicOptions configure a SymbolicExecutor. Target is the label to find
+// inputs for, written with S and T as in the assembler, or empty to only
+// look for errors. MaxPaths and MaxSteps bound the paths explored and the
+// instructions run along each of them.
+type SymbolicOptions struct {
+	Target   string
+	MaxPaths int
+	MaxSteps int
+}
+
+const (
+	defaultMaxPaths = 1000
+	defaultMaxSteps = 10000
+)
+
+type inputKind int
+
+const (
+	inputNone inputKind = iota
+	inputCharacter
+	inputNumber
+)
+
+// symbolicState is one path through the program. Values are linear
+// expressions over the inputs read so far; inputs lists the variables read
+// by Getc and Getn in order.
+type symbolicState struct {
+	pc          int
+	stack       []linear
+	heap        map[int]linear
+	callStack   []int
+	inputs      []int
+	constraints []constraint
+	steps       int
+}
+
+func (state *symbolicState) fork() *symbolicState {
+	heap := make(map[int]linear, len(state.heap))
+	for address, value := range state.heap {
+		heap[address] = value
+	}
+	return &symbolicState{
+		pc:          state.pc,
+		stack:       append([]linear{}, state.stack...),
+		heap:        heap,
+		callStack:   append([]int{}, state.callStack...),
+		inputs:      append([]int{}, state.inputs...),
+		constraints: append([]constraint{}, state.constraints...),
+		steps:       state.steps,
+	}
+}
+
+func (state *symbolicState) pop() (linear, bool) {
+	if len(state.stack) == 0 {
+		return linear{}, false
+	}
+	value := state.stack[len(state.stack)-1]
+	state.stack = state.stack[:len(state.stack)-1]
+	return value, true
+}
+
+// SymbolicExecutor explores the paths of a program with the values read by
+// Getc and Getn left symbolic. It forks at conditional jumps on symbolic
+// values, keeps the conditions of each path as linear constraints and uses
+// solve to find inputs that lead to the target label or to an error. The
+// results of multiplying two symbolic values and of dividing a symbolic
+// value are not linear and become fresh unconstrained variables, so every
+// input found is checked by running the program before it is reported.
+type SymbolicExecutor struct {
+	instructions []Instruction
+	labels       map[string]int
+	options      SymbolicOptions
+	target       int
+	variables    []symbolicVariable
+	kinds        []inputKind
+	findings     []Finding
+	found        map[string]bool
+	worklist     []*symbolicState
+
+	// Paths is the number of paths explored and Truncated is set when
+	// MaxPaths or MaxSteps stopped the exploration early.
+	Paths     int
+	Truncated bool
+}
+
+func NewSymbolicExecutor(instructions []Instruction, options SymbolicOptions) (*SymbolicExecutor, error) {
+	if options.MaxPaths <= 0 {
+		options.MaxPaths = defaultMaxPaths
+	}
+	if options.MaxSteps <= 0 {
+		options.MaxSteps = defaultMaxSteps
+	}
+
+	symbolic := &SymbolicExecutor{
+		instructions: instructions,
+		labels:       labelTable(instructions),
+		options:      options,
+		target:       -1,
+		found:        map[string]bool{},
+	}
+
+	if options.Target != "" {
+		label, err := parseLabelName(options.Target)
+		if err != nil {
+			return nil, err
+		}
+		target, ok := symbolic.labels[label]
+		if !ok {
+			return nil, fmt.Errorf("label %s is not defined", options.Target)
+		}
+		symbolic.target = target
+	}
+
+	return symbolic, nil
+}
+
+// Explore runs every path it can within the limits and returns the findings
+// confirmed by running the program, at most one per kind and instruction.
+func (symbolic *SymbolicExecutor) Explore() []Finding {
+	symbolic.worklist = []*symbolicState{{heap: map[int]linear{}}}
+
+	for len(symbolic.worklist) > 0 {
+		if symbolic.Paths >= symbolic.options.MaxPaths {
+			symbolic.Truncated = true
+			break
+		}
+		symbolic.Paths++
+
+		state := symbolic.worklist[0]
+		symbolic.worklist = symbolic.worklist[1:]
+		symbolic.run(state)
+	}
+
+	sortFindings(symbolic.findings)
+	return symbolic.findings
+}
+
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].PC != findings[j].PC {
+			return findings[i].PC < findings[j].PC
+		}
+		return findings[i].Kind < findings[j].Kind
+	})
+}
+
+func (symbolic *SymbolicExecutor) newVariable(kind inputKind) int {
+	variable := symbolicVariable{lo: -unbounded, hi: unbounded}
+	if kind == inputCharacter {
+		variable = symbolicVariable{lo: 0, hi: 0x10FFFF, prefer: 'a'}
+	}
+	symbolic.variables = append(symbolic.variables, variable)
+	symbolic.kinds = append(symbolic.kinds, kind)
+	return len(symbolic.variables) - 1
+}
+
+func (symbolic *SymbolicExecutor) solve(state *symbolicState) ([]int, bool) {
+	return solve(symbolic.variables, state.constraints)
+}
+
+// feasible adds c to the constraints of state and reports whether the path
+// can still be taken.
+func (symbolic *SymbolicExecutor) feasible(state *symbolicState, c constraint) bool {
+	state.constraints = append(state.constraints, c)
+	_, ok := symbolic.solve(state)
+	return ok
+}
+
+// concrete returns a value value can take on this path and constrains it to
+// that value, which is how symbolic heap addresses are handled.
+func (symbolic *SymbolicExecutor) concrete(state *symbolicState, value linear) (int, bool) {
+	if value.isConstant() {
+		return value.constant, true
+	}
+
+	model, ok := symbolic.solve(state)
+	if !ok {
+		return 0, false
+	}
+	n := value.eval(model)
+	state.constraints = append(state.constraints, constraint{value: value.sub(constantLinear(n)), relation: relationZero})
+	return n, true
+}
+
+// report records a finding for state when no finding of the same kind was
+// confirmed at pc yet and running the program with the solved input
+// reproduces it.
+func (symbolic *SymbolicExecutor) report(state *symbolicState, kind FindingKind, message string) {
+	key := fmt.Sprintf("%d:%d", kind, state.pc)
+	if symbolic.found[key] {
+		return
+	}
+
+	model, ok := symbolic.solve(state)
+	if !ok {
+		return
+	}
+
+	finding := Finding{Kind: kind, PC: state.pc, Message: message, Input: []string{}}
+	for _, v := range state.inputs {
+		if symbolic.kinds[v] == inputCharacter {
+			finding.Input = append(finding.Input, string(rune(model[v])))
+		} else {
+			finding.Input = append(finding.Input, strconv.Itoa(model[v]))
+		}
+	}
+
+	if symbolic.confirm(finding) {
+		symbolic.found[key] = true
+		symbolic.findings = append(symbolic.findings, finding)
+	}
+}
+
+// retrieve forks state for every stored address a symbolic address may be
+// equal to, and reports an invalid heap access when it may be none of them.
+func (symbolic *SymbolicExecutor) retrieve(state *symbolicState, address linear) {
+	addresses := make([]int, 0, len(state.heap))
+	for a := range state.heap {
+		addresses = append(addresses, a)
+	}
+	sort.Ints(addresses)
+
+	for _, a := range addresses {
+		offset := address.sub(constantLinear(a))
+		other := state.fork()
+		if symbolic.feasible(other, constraint{value: offset, relation: relationZero}) {
+			other.stack = append(other.stack, state.heap[a])
+			other.pc++
+			symbolic.worklist = append(symbolic.worklist, other)
+		}
+		state.constraints = append(state.constraints, constraint{value: offset, relation: relationNonZero})
+	}
+
+	symbolic.report(state, FindingRuntimeError, "invalid heap access")
+}
+
+// run follows state until the path ends or forks.
+func (symbolic *SymbolicExecutor) run(state *symbolicState) {
+	for state.pc < len(symbolic.instructions) {
+		if state.steps >= symbolic.options.MaxSteps {
+			symbolic.Truncated = true
+			return
+		}
+		state.steps++
+
+		if state.pc == symbolic.target {
+			symbolic.report(state, FindingTarget, fmt.Sprintf("label %s is reached", symbolic.options.Target))
+			return
+		}
+
+		if !symbolic.step(state) {
+			return
+		}
+	}
+}
+
+// step runs the instruction at state.pc and reports whether the path goes
+// on. Like the executor, jumps continue at the MarkLabel of their label.
+func (symbolic *SymbolicExecutor) step(state *symbolicState) bool {
+	next := state.pc + 1
+	fail := func(message string) bool {
+		symbolic.report(state, FindingRuntimeError, message)
+		return false
+	}
+	pop := func(n int) ([]linear, bool) {
+		values := make([]linear, n)
+		for i := range values {
+			value, ok := state.pop()
+			if !ok {
+				return nil, false
+			}
+			values[i] = value
+		}
+		return values, true
+	}
+	jump := func(label string) bool {
+		target, ok := symbolic.labels[label]
+		if !ok {
+			return fail("label not found")
+		}
+		next = target
+		return true
+	}
+
+	instruction := symbolic.instructions[state.pc]
+	switch i := instruction.(type) {
+	case Push:
+		state.stack = append(state.stack, constantLinear(i.value))
+	case Duplicate:
+		values, ok := pop(1)
+		if !ok {
+			return fail("stack is empty")
+		}
+		state.stack = append(state.stack, values[0], values[0])
+	case Swap:
+		values, ok := pop(2)
+		if !ok {
+			return fail("stack is empty")
+		}
+		state.stack = append(state.stack, values[0], values[1])
+	case Discard:
+		state.pop()
+	case Addition, Subtraction, Multiplication, Division, Modulo:
+		values, ok := pop(2)
+		if !ok {
+			return fail("stack is empty")
+		}
+		result, ok := symbolic.arithmetic(state, instruction, values[0], values[1])
+		if !ok {
+			return false
+		}
+		state.stack = append(state.stack, result)
+	case Store:
+		values, ok := pop(2)
+		if !ok {
+			return fail("stack is empty")
+		}
+		address, ok := symbolic.concrete(state, values[1])
+		if !ok {
+			return false
+		}
+		state.heap[address] = values[0]
+	case Retrieve:
+		values, ok := pop(1)
+		if !ok {
+			return fail("stack is empty")
+		}
+		if !values[0].isConstant() {
+			symbolic.retrieve(state, values[0])
+			return false
+		}
+		value, stored := state.heap[values[0].constant]
+		if !stored {
+			return fail("invalid heap access")
+		}
+		state.stack = append(state.stack, value)
+	case Getc, Getn:
+		kind := inputNumber
+		if _, ok := instruction.(Getc); ok {
+			kind = inputCharacter
+		}
+		values, ok := pop(1)
+		if !ok {
+			return fail("stack is empty")
+		}
+		address, ok := symbolic.concrete(state, values[0])
+		if !ok {
+			return false
+		}
+
+		v := symbolic.newVariable(kind)
+		state.inputs = append(state.inputs, v)
+		if kind == inputCharacter {
+			// A line can not start with the line break that ends it.
+			for _, excluded := range []int{'\n', '\r'} {
+				state.constraints = append(state.constraints, constraint{value: variableLinear(v).sub(constantLinear(excluded)), relation: relationNonZero})
+			}
+		}
+		state.heap[address] = variableLinear(v)
+	case Putc, Putn:
+		if _, ok := pop(1); !ok {
+			return fail("stack is empty")
+		}
+	case CallSubroutine:
+		state.callStack = append(state.callStack, state.pc)
+		if !jump(i.label) {
+			return false
+		}
+	case EndSubroutine:
+		if len(state.callStack) == 0 {
+			return fail("call stack is empty")
+		}
+		next = state.callStack[len(state.callStack)-1] + 1
+		state.callStack = state.callStack[:len(state.callStack)-1]
+	case JumpLabel:
+		if !jump(i.label) {
+			return false
+		}
+	case JumpLabelWhenZero, JumpLabelWhenNegative:
+		values, ok := pop(1)
+		if !ok {
+			return fail("stack is empty")
+		}
+		label, _ := jumpLabel(instruction)
+		taken, notTaken := relationZero, relationNonZero
+		if _, ok := instruction.(JumpLabelWhenNegative); ok {
+			taken, notTaken = relationNegative, relationNonNegative
+		}
+
+		if values[0].isConstant() {
+			if (constraint{value: values[0], relation: taken}).holds(nil) && !jump(label) {
+				return false
+			}
+			break
+		}
+
+		// Both directions are queued so paths are explored in the order
+		// of the number of branches they take.
+		other := state.fork()
+		if symbolic.feasible(other, constraint{value: values[0], relation: taken}) {
+			if _, defined := symbolic.labels[label]; defined {
+				other.pc = symbolic.labels[label]
+				symbolic.worklist = append(symbolic.worklist, other)
+			} else {
+				symbolic.report(other, FindingRuntimeError, "label not found")
+			}
+		}
+		if symbolic.feasible(state, constraint{value: values[0], relation: notTaken}) {
+			state.pc++
+			symbolic.worklist = append(symbolic.worklist, state)
+		}
+		return false
+	case EndProgram:
+		return false
+	}
+
+	state.pc = next
+	return true
+}
+
+// arithmetic follows the operand order of the instructions: lhs is the top
+// of the stack and rhs the value below it.
+func (symbolic *SymbolicExecutor) arithmetic(state *symbolicState, instruction Instruction, lhs, rhs linear) (linear, bool) {
+	switch instruction.(type) {
+	case Addition:
+		return lhs.add(rhs), true
+	case Subtraction:
+		return lhs.sub(rhs), true
+	case Multiplication:
+		if lhs.isConstant() {
+			return rhs.scale(lhs.constant), true
+		}
+		if rhs.isConstant() {
+			return lhs.scale(rhs.constant), true
+		}
+		return variableLinear(symbolic.newVariable(inputNone)), true
+	}
+
+	message := fmt.Sprintf("%s divides by zero", Mnemonic(instruction))
+	if rhs.isConstant() {
+		if rhs.constant == 0 {
+			symbolic.report(state, FindingDivisionByZero, message)
+			return linear{}, false
+		}
+		if lhs.isConstant() {
+			if _, ok := instruction.(Division); ok {
+				return constantLinear(lhs.constant / rhs.constant), true
+			}
+			return constantLinear(lhs.constant % rhs.constant), true
+		}
+	} else {
+		zero := state.fork()
+		if symbolic.feasible(zero, constraint{value: rhs, relation: relationZero}) {
+			symbolic.report(zero, FindingDivisionByZero, message)
+		}
+		if !symbolic.feasible(state, constraint{value: rhs, relation: relationNonZero}) {
+			return linear{}, false
+		}
+	}
+
+	return variableLinear(symbolic.newVariable(inputNone)), true
+}
+
+var (
+	errFindingReproduced = errors.New("finding reproduced")
+	errStepLimit         = errors.New("step limit reached")
+)
+
+// findingCheck is the Hook confirm runs the program with. It stops the
+// executor with errFindingReproduced once the finding happens and with
+// another error after MaxSteps instructions.
+type findingCheck struct {
+	finding  Finding
+	target   int
+	maxSteps int
+	steps    int
+}
+
+func (check *findingCheck) Before(executor *Executor, pc int) error {
+	if check.steps++; check.steps > check.maxSteps {
+		return errStepLimit
+	}
+
+	switch check.finding.Kind {
+	case FindingTarget:
+		if pc == check.target {
+			return errFindingReproduced
+		}
+	case FindingDivisionByZero:
+		switch executor.instructions[pc].(type) {
+		case Division, Modulo:
+			stack := executor.stack
+			if pc == check.finding.PC && len(stack) >= 2 && stack[len(stack)-2] == 0 {
+				return errFindingReproduced
+			}
+		}
+	}
+	return nil
+}
+
+func (check *findingCheck) After(executor *Executor, pc int) error {
+	if check.finding.Kind == FindingTarget && executor.programCounter == check.target {
+		return errFindingReproduced
+	}
+	return nil
+}
+
+// confirm runs the program with the input of finding and reports whether
+// it leads to the same finding.
+func (symbolic *SymbolicExecutor) confirm(finding Finding) bool {
+	executor := &Executor{instructions: symbolic.instructions}
+	executor.SetInput(strings.NewReader(finding.InputText()))
+	executor.SetOutput(ioutil.Discard)
+	executor.AddHook(&findingCheck{finding: finding, target: symbolic.target, maxSteps: symbolic.options.MaxSteps})
+
+	err := executor.Run()
+	if err == errFindingReproduced {
+		return true
+	}
+	return finding.Kind == FindingRuntimeError && err != nil && err != errStepLimit && executor.programCounter == finding.PC
+}
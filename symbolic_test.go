@@ -0,0 +1,90 @@
+package whitespace_go
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func explore(t *testing.T, source string, options SymbolicOptions) []Finding {
+	instructions, err := Assemble(source)
+	if err != nil {
+		t.Fatalf("can not assemble: %s", err.Error())
+	}
+
+	symbolic, err := NewSymbolicExecutor(instructions, options)
+	if err != nil {
+		t.Fatalf("can not explore: %s", err.Error())
+	}
+	return symbolic.Explore()
+}
+
+func TestSymbolicExecutorReachesTarget(t *testing.T) {
+	findings := explore(t, `
+		push 0 getn push 0 retrieve push -42 add jz S end
+		label S end`, SymbolicOptions{Target: "S"})
+
+	assert.Equal(t, []Finding{{Kind: FindingTarget, PC: 8, Message: "label S is reached", Input: []string{"42"}}}, findings)
+}
+
+func TestSymbolicExecutorCharacterInput(t *testing.T) {
+	findings := explore(t, `
+		push 0 getc
+		push 0 retrieve push -100 add jn S
+		push -300 push 0 retrieve push 3 mul add jz T end
+		label S end
+		label T end`, SymbolicOptions{Target: "T"})
+
+	assert.Equal(t, []Finding{{Kind: FindingTarget, PC: 17, Message: "label T is reached", Input: []string{"d"}}}, findings)
+	assert.Equal(t, "d\n", findings[0].InputText())
+}
+
+func TestSymbolicExecutorFindsErrors(t *testing.T) {
+	findings := explore(t, `
+		push 0 getn push 1 getn
+		push 1 retrieve push 0 retrieve div
+		push 0 retrieve push -17 add jn S end
+		label S ret`, SymbolicOptions{})
+
+	assert.Equal(t, []Finding{
+		{Kind: FindingDivisionByZero, PC: 8, Message: "div divides by zero", Input: []string{"0", "0"}},
+		{Kind: FindingRuntimeError, PC: 16, Message: "call stack is empty", Input: []string{"0", "-1"}},
+	}, findings)
+}
+
+func TestSymbolicExecutorInvalidHeapAccess(t *testing.T) {
+	findings := explore(t, `push 0 getn push 0 retrieve retrieve end`, SymbolicOptions{})
+
+	assert.Equal(t, []Finding{{Kind: FindingRuntimeError, PC: 4, Message: "invalid heap access", Input: []string{"-1"}}}, findings)
+}
+
+func TestSymbolicExecutorLimits(t *testing.T) {
+	instructions, _ := Assemble(`
+		push 0 getn
+		label S push 0 retrieve jz T push 0 push -1 push 0 retrieve add store jump S
+		label T end`)
+	symbolic, _ := NewSymbolicExecutor(instructions, SymbolicOptions{Target: "T", MaxPaths: 20})
+
+	assert.Equal(t, []Finding{{Kind: FindingTarget, PC: 13, Message: "label T is reached", Input: []string{"0"}}}, symbolic.Explore())
+	assert.Equal(t, 20, symbolic.Paths)
+	assert.True(t, symbolic.Truncated)
+
+	_, err := NewSymbolicExecutor(instructions, SymbolicOptions{Target: "TT"})
+	assert.EqualError(t, err, "label TT is not defined")
+}
+
+func TestSolve(t *testing.T) {
+	x, y := variableLinear(0), variableLinear(1)
+	variables := []symbolicVariable{{lo: -unbounded, hi: unbounded}, {lo: 0, hi: 10}}
+
+	model, ok := solve(variables, []constraint{
+		{value: x.scale(2).add(y).sub(constantLinear(25)), relation: relationZero},
+		{value: y.sub(constantLinear(5)), relation: relationNonNegative},
+		{value: y.sub(constantLinear(5)), relation: relationNonZero},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 25, 2*model[0]+model[1])
+	assert.True(t, model[1] > 5)
+
+	_, ok = solve(variables, []constraint{{value: x.scale(2).sub(constantLinear(7)), relation: relationZero}})
+	assert.False(t, ok)
+}
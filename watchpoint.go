@@ -0,0 +1,197 @@
+package whitespace_go
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type WatchKind int
+
+const (
+	WatchHeapWrite WatchKind = iota
+	WatchHeapRead
+	WatchHeapAccess
+	WatchStackAbove
+	WatchStackBelow
+	WatchCallDepth
+)
+
+// Watchpoint describes a condition the Watcher reports on. Address is used by
+// the heap kinds and Depth by the stack and call stack kinds. When Break is
+// set the program stops once the watchpoint triggers, otherwise the hit is
+// only logged.
+type Watchpoint struct {
+	Kind    WatchKind
+	Address int
+	Depth   int
+	Break   bool
+}
+
+// ParseWatchpoint parses the command line form of a watchpoint:
+//
+//	write:ADDR  read:ADDR  access:ADDR  stack>N  stack<N  calls:N
+func ParseWatchpoint(spec string) (Watchpoint, error) {
+	prefixes := []struct {
+		prefix string
+		kind   WatchKind
+	}{
+		{"write:", WatchHeapWrite},
+		{"read:", WatchHeapRead},
+		{"access:", WatchHeapAccess},
+		{"stack>", WatchStackAbove},
+		{"stack<", WatchStackBelow},
+		{"calls:", WatchCallDepth},
+	}
+
+	for _, p := range prefixes {
+		if !strings.HasPrefix(spec, p.prefix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimPrefix(spec, p.prefix))
+		if err != nil {
+			return Watchpoint{}, fmt.Errorf("invalid watchpoint %q: %s", spec, err.Error())
+		}
+
+		switch p.kind {
+		case WatchHeapWrite, WatchHeapRead, WatchHeapAccess:
+			return Watchpoint{Kind: p.kind, Address: n}, nil
+		default:
+			if n < 0 {
+				return Watchpoint{}, fmt.Errorf("invalid watchpoint %q: depth must not be negative", spec)
+			}
+			return Watchpoint{Kind: p.kind, Depth: n}, nil
+		}
+	}
+
+	return Watchpoint{}, fmt.Errorf("invalid watchpoint %q", spec)
+}
+
+func (watchpoint Watchpoint) String() string {
+	switch watchpoint.Kind {
+	case WatchHeapWrite:
+		return fmt.Sprintf("write:%d", watchpoint.Address)
+	case WatchHeapRead:
+		return fmt.Sprintf("read:%d", watchpoint.Address)
+	case WatchHeapAccess:
+		return fmt.Sprintf("access:%d", watchpoint.Address)
+	case WatchStackAbove:
+		return fmt.Sprintf("stack>%d", watchpoint.Depth)
+	case WatchStackBelow:
+		return fmt.Sprintf("stack<%d", watchpoint.Depth)
+	default:
+		return fmt.Sprintf("calls:%d", watchpoint.Depth)
+	}
+}
+
+func (watchpoint Watchpoint) watchesWrite(address int) bool {
+	return (watchpoint.Kind == WatchHeapWrite || watchpoint.Kind == WatchHeapAccess) && watchpoint.Address == address
+}
+
+func (watchpoint Watchpoint) watchesRead(address int) bool {
+	return (watchpoint.Kind == WatchHeapRead || watchpoint.Kind == WatchHeapAccess) && watchpoint.Address == address
+}
+
+// Watcher is a Hook that checks its watchpoints around every instruction.
+// Heap watchpoints trigger on each matching Store, Getc, Getn or Retrieve;
+// depth watchpoints trigger when the depth crosses the watched value.
+type Watcher struct {
+	watchpoints []Watchpoint
+	positions   []Position
+	log         io.Writer
+	writing     bool
+	address     int
+	stackDepth  int
+	callDepth   int
+}
+
+func NewWatcher(watchpoints []Watchpoint, positions []Position, log io.Writer) *Watcher {
+	return &Watcher{
+		watchpoints: watchpoints,
+		positions:   positions,
+		log:         log,
+	}
+}
+
+func (watcher *Watcher) Before(executor *Executor, pc int) error {
+	watcher.stackDepth = len(executor.stack)
+	watcher.callDepth = len(executor.callStack)
+	watcher.writing = false
+
+	switch executor.instructions[pc].(type) {
+	case Store:
+		if len(executor.stack) >= 2 {
+			watcher.writing = true
+			watcher.address = executor.stack[len(executor.stack)-2]
+		}
+	case Getc, Getn:
+		if len(executor.stack) >= 1 {
+			watcher.writing = true
+			watcher.address = executor.stack[len(executor.stack)-1]
+		}
+	case Retrieve:
+		if len(executor.stack) == 0 {
+			break
+		}
+		address := executor.stack[len(executor.stack)-1]
+		for _, watchpoint := range watcher.watchpoints {
+			if watchpoint.watchesRead(address) {
+				message := fmt.Sprintf("heap[%d] read (value %d)", address, executor.heap[address])
+				if err := watcher.trigger(executor, pc, watchpoint, message); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) After(executor *Executor, pc int) error {
+	for _, watchpoint := range watcher.watchpoints {
+		var message string
+
+		switch watchpoint.Kind {
+		case WatchHeapWrite, WatchHeapAccess:
+			if watcher.writing && watchpoint.watchesWrite(watcher.address) {
+				message = fmt.Sprintf("heap[%d] written (value %d)", watcher.address, executor.heap[watcher.address])
+			}
+		case WatchStackAbove:
+			if watcher.stackDepth <= watchpoint.Depth && len(executor.stack) > watchpoint.Depth {
+				message = fmt.Sprintf("stack depth %d exceeds %d", len(executor.stack), watchpoint.Depth)
+			}
+		case WatchStackBelow:
+			if watcher.stackDepth >= watchpoint.Depth && len(executor.stack) < watchpoint.Depth {
+				message = fmt.Sprintf("stack depth %d dropped below %d", len(executor.stack), watchpoint.Depth)
+			}
+		case WatchCallDepth:
+			if watcher.callDepth < watchpoint.Depth && len(executor.callStack) >= watchpoint.Depth {
+				message = fmt.Sprintf("call stack depth reached %d", len(executor.callStack))
+			}
+		}
+
+		if message != "" {
+			if err := watcher.trigger(executor, pc, watchpoint, message); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) trigger(executor *Executor, pc int, watchpoint Watchpoint, message string) error {
+	location := fmt.Sprintf("%s at instruction %d (%s)", message, pc, Mnemonic(executor.instructions[pc]))
+	if watchpoint.Break {
+		return watchpointError(pc, watchpoint, location)
+	}
+
+	if position, ok := positionAt(watcher.positions, pc); ok {
+		location = fmt.Sprintf("%s, line %d, column %d", location, position.Line, position.Column)
+	}
+
+	fmt.Fprintf(watcher.log, "Watchpoint %s: %s\n", watchpoint, location)
+	return nil
+}
@@ -0,0 +1,107 @@
+package whitespace_go
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseWatchpoint(t *testing.T) {
+	specs := []string{"write:5", "read:-1", "access:0", "stack>10", "stack<1", "calls:3"}
+
+	expectedWatchpoints := []Watchpoint{
+		{Kind: WatchHeapWrite, Address: 5},
+		{Kind: WatchHeapRead, Address: -1},
+		{Kind: WatchHeapAccess, Address: 0},
+		{Kind: WatchStackAbove, Depth: 10},
+		{Kind: WatchStackBelow, Depth: 1},
+		{Kind: WatchCallDepth, Depth: 3},
+	}
+
+	for i, spec := range specs {
+		watchpoint, err := ParseWatchpoint(spec)
+		if err != nil {
+			t.Errorf("expected %s to be parsed, but raise error %s", spec, err.Error())
+		}
+
+		assert.Equal(t, expectedWatchpoints[i], watchpoint)
+		assert.Equal(t, spec, watchpoint.String())
+	}
+
+	for _, spec := range []string{"heap:1", "write:x", "stack>-1"} {
+		_, err := ParseWatchpoint(spec)
+		assert.NotNil(t, err)
+	}
+}
+
+func heapProgram() []Instruction {
+	return []Instruction{
+		Push{value: 5},
+		Push{value: 42},
+		Store{},
+		Push{value: 5},
+		Retrieve{},
+		EndProgram{},
+	}
+}
+
+func TestWatcherLogsHeapAccess(t *testing.T) {
+	var log bytes.Buffer
+	watchpoints := []Watchpoint{{Kind: WatchHeapAccess, Address: 5}}
+	executor := &Executor{instructions: heapProgram()}
+	positions := make([]Position, 6)
+	for pc := range positions {
+		positions[pc] = Position{Line: pc + 1, Column: 2}
+	}
+	executor.AddHook(NewWatcher(watchpoints, positions, &log))
+
+	err := executor.Run()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Watchpoint access:5: heap[5] written (value 42) at instruction 2 (store), line 3, column 2\n"+
+		"Watchpoint access:5: heap[5] read (value 42) at instruction 4 (retrieve), line 5, column 2\n", log.String())
+}
+
+func TestWatcherBreaksOnStackDepth(t *testing.T) {
+	var log bytes.Buffer
+	watchpoints := []Watchpoint{{Kind: WatchStackAbove, Depth: 1, Break: true}}
+	executor := &Executor{instructions: heapProgram()}
+	executor.AddHook(NewWatcher(watchpoints, nil, &log))
+
+	err := executor.Run()
+
+	assert.EqualError(t, err, "Watchpoint stack>1: stack depth 2 exceeds 1 at instruction 1 (push 42)")
+	if watchpointErr, ok := err.(*WatchpointError); assert.True(t, ok) {
+		assert.Equal(t, 1, watchpointErr.PC)
+		assert.Equal(t, watchpoints[0], watchpointErr.Watchpoint)
+	}
+	assert.Equal(t, 1, executor.programCounter)
+	assert.Equal(t, "", log.String())
+}
+
+func TestWatcherCallDepth(t *testing.T) {
+	var log bytes.Buffer
+	watchpoints := []Watchpoint{{Kind: WatchCallDepth, Depth: 1}}
+	executor := &Executor{instructions: profiledSubroutineProgram()}
+	executor.AddHook(NewWatcher(watchpoints, nil, &log))
+
+	executor.Run()
+
+	assert.Equal(t, "Watchpoint calls:1: call stack depth reached 1 at instruction 0 (call T)\n"+
+		"Watchpoint calls:1: call stack depth reached 1 at instruction 1 (call T)\n", log.String())
+}
+
+func TestWatcherBreaksAfterJump(t *testing.T) {
+	instructions, err := Assemble("push 1 push 2 jump S label S discard end")
+	assert.Nil(t, err)
+	watchpoints := []Watchpoint{{Kind: WatchStackBelow, Depth: 2, Break: true}}
+	executor := &Executor{instructions: instructions, labels: labelTable(instructions)}
+	executor.AddHook(NewWatcher(watchpoints, nil, &bytes.Buffer{}))
+
+	err = executor.Run()
+
+	assert.EqualError(t, err, "Watchpoint stack<2: stack depth 1 dropped below 2 at instruction 4 (discard)")
+	if watchpointErr, ok := err.(*WatchpointError); assert.True(t, ok) {
+		assert.Equal(t, 4, watchpointErr.PC)
+	}
+}